@@ -3,6 +3,7 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"request-policy/internal/web"
 )
@@ -14,5 +15,26 @@ func main() {
 	log.Println("Client-Server Simulation")
 
 	dashboard := web.NewDashboard()
+	if dir := os.Getenv("BEHAVIORS_DIR"); dir != "" {
+		log.Printf("Behavior file references enabled, base directory: %s", dir)
+		dashboard.SetBehaviorsDir(dir)
+	}
+	if value := os.Getenv("MAX_SIMULATION_DURATION"); value != "" {
+		limit, err := time.ParseDuration(value)
+		if err != nil {
+			log.Fatalf("Invalid MAX_SIMULATION_DURATION '%s': %v", value, err)
+		}
+		log.Printf("Simulation max duration: %v (0 disables)", limit)
+		dashboard.SetMaxDuration(limit)
+	}
+	if basePath := os.Getenv("BASE_PATH"); basePath != "" {
+		log.Printf("Routes are served under base path: %s", basePath)
+		dashboard.SetBasePath(basePath)
+	}
+	if addr := os.Getenv("ADDR"); addr != "" {
+		dashboard.SetAddr(addr)
+	} else if port := os.Getenv("PORT"); port != "" {
+		dashboard.SetAddr(":" + port)
+	}
 	dashboard.ListenAndServe()
 }
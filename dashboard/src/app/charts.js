@@ -79,7 +79,7 @@ const _rps = () => [
   [], // client_sent_req
   [], // client_success_resp
   [], // client_error_resp
-  [], // network_failed_reqs
+  [], // network_failed_reqs + client_timeout_req
   [], // server_received_req
   [], // server_success_resp
   [], // server_error_resp
@@ -97,7 +97,7 @@ const $counters = createStore(_rps())
       creq.push(m.client_sent_req)
       csuc.push(m.client_success_resp)
       cerr.push(m.client_error_resp)
-      nerr.push(m.network_failed_reqs)
+      nerr.push(m.network_failed_reqs + (m.client_timeout_req ?? 0))
       sreq.push(m.server_received_req)
       ssuc.push(m.server_success_resp)
       serr.push(m.server_error_resp)
@@ -176,16 +176,16 @@ function rps(t, v) {
 
 const _responseTime = () => [
   [], // timestamp
-  [], // min_response_time
-  [], // max_response_time
-  [], // avg_response_time
-  [], // p50_response_time
-  [], // p80_response_time
-  [], // p95_response_time
-  [], // min_request_latency
-  [], // max_request_latency
-  [], // min_response_latency
-  [], // max_response_latency
+  [], // min_response_time_ms
+  [], // max_response_time_ms
+  [], // avg_response_time_ms
+  [], // p50_response_time_ms
+  [], // p80_response_time_ms
+  [], // p95_response_time_ms
+  [], // min_request_latency_ms
+  [], // max_request_latency_ms
+  [], // min_response_latency_ms
+  [], // max_response_latency_ms
 ]
 
 export const $responseTime = createStore(_responseTime())
@@ -195,16 +195,16 @@ export const $responseTime = createStore(_responseTime())
     metrics.data,
     ([t, min, max, avg, p50, p80, p95, lql, hql, lal, hal], m) => {
       t.push(m.timestamp / 1000)
-      min.push(min.length ? m.min_response_time || null : null)
-      max.push(max.length ? m.max_response_time || null : null)
-      avg.push(avg.length ? m.avg_response_time || null : null)
-      p50.push(p50.length ? m.p50_response_time || null : null)
-      p80.push(p80.length ? m.p80_response_time || null : null)
-      p95.push(p95.length ? m.p95_response_time || null : null)
-      lql.push(lql.length ? m.min_request_latency || null : null)
-      hql.push(hql.length ? m.max_request_latency || null : null)
-      lal.push(lal.length ? m.min_response_latency || null : null)
-      hal.push(hal.length ? m.max_response_latency || null : null)
+      min.push(min.length ? m.min_response_time_ms || null : null)
+      max.push(max.length ? m.max_response_time_ms || null : null)
+      avg.push(avg.length ? m.avg_response_time_ms || null : null)
+      p50.push(p50.length ? m.p50_response_time_ms || null : null)
+      p80.push(p80.length ? m.p80_response_time_ms || null : null)
+      p95.push(p95.length ? m.p95_response_time_ms || null : null)
+      lql.push(lql.length ? m.min_request_latency_ms || null : null)
+      hql.push(hql.length ? m.max_request_latency_ms || null : null)
+      lal.push(lal.length ? m.min_response_latency_ms || null : null)
+      hal.push(hal.length ? m.max_response_latency_ms || null : null)
       return [t, min, max, avg, p50, p80, p95, lql, hql, lal, hal]
     }
   )
@@ -12,12 +12,15 @@ export const left = createEvent()
 export const simulation_reset = createEvent()
 export const simulation_started = createEvent()
 export const simulation_stopped = createEvent()
+export const simulation_paused = createEvent()
+export const simulation_resumed = createEvent()
 export const client_config_added = createEvent()
 export const client_configs_cleared = createEvent()
 export const client_config_deleted = createEvent()
 export const client_config_updated = createEvent()
 export const server_behavior_updated = createEvent()
 export const network_behavior_updated = createEvent()
+export const config_imported = createEvent()
 
 export const $clients = createStore([]) //
   .on(joined, (_, payload) => moveToLast(payload.payload.all, name))
@@ -50,17 +53,26 @@ split({
     simulation_reset,
     simulation_started,
     simulation_stopped,
+    simulation_paused,
+    simulation_resumed,
     client_config_added,
     client_configs_cleared,
     client_config_deleted,
     client_config_updated,
     server_behavior_updated,
     network_behavior_updated,
+    config_imported,
   },
 })
 
 sample({
-  clock: [simulation_started, simulation_stopped, simulation_reset],
+  clock: [
+    simulation_started,
+    simulation_stopped,
+    simulation_paused,
+    simulation_resumed,
+    simulation_reset,
+  ],
   target: simulation.statusQuery.refresh,
 })
 
@@ -70,17 +82,18 @@ sample({
     client_configs_cleared,
     client_config_deleted,
     client_config_updated,
+    config_imported,
   ],
   target: clients.clientsQuery.refresh,
 })
 
 sample({
-  clock: [server_behavior_updated],
+  clock: [server_behavior_updated, config_imported],
   target: server.serverQuery.refresh,
 })
 
 sample({
-  clock: [network_behavior_updated],
+  clock: [network_behavior_updated, config_imported],
   target: network.networkQuery.refresh,
 })
 
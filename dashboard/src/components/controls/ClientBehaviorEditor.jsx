@@ -46,11 +46,13 @@ const hooks = [
   [
     'on_fail', //
     `on_fail(req, err):
+  # req["category"] is one of "network", "timeout", "server_error", "rejected", "dropped"
   pass`,
   ],
   [
     'on_retry', //
     `on_retry(req, resp, err):
+  # req["category"] is one of "network", "timeout", "server_error", "rejected", "dropped"
   # might return dict { "allow": bool, "delay": int }
   pass`,
   ],
@@ -333,7 +333,11 @@ export function ClientConfig() {
   }
 
   const handleAddClientsConfig = () => {
-    const newId = Math.random().toString(16).slice(2, 10)
+    // Stable group ids ("group-N"), so that metrics by group can be compared across runs
+    const groups = clientGroups() || []
+    let n = groups.length + 1
+    while (groups.some((c) => c.id === `group-${n}`)) n++
+    const newId = `group-${n}`
     create({
       id: newId,
       count: newClientCount(),
@@ -6,6 +6,9 @@ import (
 	"time"
 )
 
+// metricsInterval is how often metrics snapshot is taken and published
+const metricsInterval = 200 * time.Millisecond
+
 // MetricsCtxWatcher represents a context and a metrics function for handle simulation runs
 type MetricsCtxWatcher struct {
 	ctx     context.Context
@@ -49,7 +52,8 @@ func (me *MetricsEmitter) Unsubscribe(subCh chan map[string]any) {
 	me.events.Unsubscribe(subCh)
 }
 
-// run starts the metrics emitter
+// run starts the metrics emitter, every snapshot is extended with `emitter_snapshot_ms` (time taken to compute it)
+// and `emitter_behind` (number of snapshots of the run which took longer than the interval)
 func (me *MetricsEmitter) run() {
 	defer log.Println("MetricsEmitter: Stopped")
 	for {
@@ -58,7 +62,8 @@ func (me *MetricsEmitter) run() {
 
 		ctx := run.ctx
 		metrics := run.metrics
-		ticker := time.NewTicker(200 * time.Millisecond)
+		ticker := time.NewTicker(metricsInterval)
+		var behind int64
 
 	run:
 		for {
@@ -68,7 +73,22 @@ func (me *MetricsEmitter) run() {
 				break run
 
 			case <-ticker.C:
+				start := time.Now()
 				snapshot := metrics()
+				elapsed := time.Since(start)
+
+				// Snapshot took longer than the interval, skip the tick which was due meanwhile,
+				// so that the next snapshot is taken a full interval later instead of right away
+				if elapsed > metricsInterval {
+					if behind == 0 {
+						log.Printf("MetricsEmitter: Warning: Metrics snapshot took %v, longer than interval %v, metrics cadence is degraded\n", elapsed, metricsInterval)
+					}
+					behind++
+					ticker.Reset(metricsInterval)
+				}
+				snapshot["emitter_snapshot_ms"] = float64(elapsed) / float64(time.Millisecond)
+				snapshot["emitter_behind"] = behind
+
 				// log.Printf("MetricsEmitter: Publishing metrics: %+v\n", snapshot)
 				me.events.Publish(snapshot)
 				// log.Printf("MetricsEmitter: Published: %s\n", snapshot)
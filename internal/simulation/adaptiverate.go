@@ -0,0 +1,97 @@
+package simulation
+
+import (
+	"sync"
+	"time"
+)
+
+// Defaults for adaptive rate parameters which are not set
+const (
+	defaultAdaptiveIncreaseRPS    = 1.0
+	defaultAdaptiveDecreaseFactor = 0.5
+	defaultAdaptiveMinRPS         = 0.1
+)
+
+// AdaptiveRate configures AIMD (additive increase, multiplicative decrease) control of client request rate,
+// like in TCP congestion control: rate grows slowly while responses are fast and successful,
+// and is cut on slow or failed responses
+type AdaptiveRate struct {
+	Enabled        bool
+	TargetLatency  time.Duration // Responses slower than this decrease rate (0 decreases on failures only)
+	IncreaseRPS    float64       // Rate increase per second of good responses, in requests per second (1 if not positive)
+	DecreaseFactor float64       // Rate multiplier on slow or failed response, in (0,1) (0.5 if not set)
+	MinRPS         float64       // Lowest rate (0.1 if not positive)
+	MaxRPS         float64       // Highest rate (0 is unlimited)
+}
+
+// rateController adjusts client request rate by AIMD, starting from the configured request rate
+type rateController struct {
+	config       AdaptiveRate
+	rps          float64
+	lastDecrease time.Time
+	mu           sync.Mutex
+}
+
+// newRateController creates AIMD controller, or returns nil if adaptive rate is disabled
+func newRateController(config AdaptiveRate) *rateController {
+	if !config.Enabled {
+		return nil
+	}
+
+	if config.IncreaseRPS <= 0 {
+		config.IncreaseRPS = defaultAdaptiveIncreaseRPS
+	}
+	if config.DecreaseFactor <= 0 || config.DecreaseFactor >= 1 {
+		config.DecreaseFactor = defaultAdaptiveDecreaseFactor
+	}
+	if config.MinRPS <= 0 {
+		config.MinRPS = defaultAdaptiveMinRPS
+	}
+
+	return &rateController{config: config}
+}
+
+// reset sets current rate from the configured request interval
+func (rc *rateController) reset(requestRate time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.rps = rc.clamp(float64(time.Second) / float64(requestRate))
+	rc.lastDecrease = time.Time{}
+}
+
+// interval returns current time between requests
+func (rc *rateController) interval() time.Duration {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return time.Duration(float64(time.Second) / rc.rps)
+}
+
+// observe adjusts rate by response of the request sent at sentAt.
+// Rate is decreased at most once per window of in-flight requests: responses to requests
+// sent before the last decrease do not decrease it again, as they reflect the old rate.
+func (rc *rateController) observe(sentAt time.Time, outcome Outcome, responseTime time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	slow := rc.config.TargetLatency > 0 && responseTime > rc.config.TargetLatency
+	if outcome.IsSuccess() && !slow {
+		// Additive increase, spread over responses received in a second
+		rc.rps = rc.clamp(rc.rps + rc.config.IncreaseRPS/rc.rps)
+		return
+	}
+
+	if sentAt.Before(rc.lastDecrease) {
+		return
+	}
+	rc.rps = rc.clamp(rc.rps * rc.config.DecreaseFactor)
+	rc.lastDecrease = time.Now()
+}
+
+// clamp limits rate to the configured bounds
+func (rc *rateController) clamp(rps float64) float64 {
+	rps = max(rps, rc.config.MinRPS)
+	if rc.config.MaxRPS > 0 {
+		rps = min(rps, rc.config.MaxRPS)
+	}
+	return rps
+}
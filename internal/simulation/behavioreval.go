@@ -0,0 +1,185 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	starlarkjson "go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// maxEvalSteps limits execution of evaluated hook, so that a looping script cannot hang the caller
+const maxEvalSteps = 10_000_000
+
+// HookEval describes a single behavior hook call with synthetic request and response
+type HookEval struct {
+	Script   string         // Script source, or file reference in the behaviors directory
+	Hook     string         // Hook name, like "on_request" or "on_retry"
+	Request  Request        // Synthetic request, its Meta is replaced with Meta below
+	Meta     map[string]any // Request metadata
+	Response *Response      // Synthetic response for on_response, on_error and on_retry (nil if none)
+	Error    string         // Error passed to on_fail and on_retry (empty if none)
+}
+
+// HookEvalResult holds what the hook returned and how it changed the request
+type HookEvalResult struct {
+	Allow          bool
+	DelayMs        int
+	TimeoutMs      int
+	Meta           map[string]any // Request metadata after the hook
+	IdempotencyKey string
+	TraceId        string
+	Output         []string // Lines printed by print() and log()
+	Error          string   // Hook runtime error (empty if hook succeeded)
+}
+
+// EvaluateHook compiles behavior script and runs a single hook against synthetic request, without running a simulation,
+// returns error if script does not compile or hook is unknown or not defined
+func EvaluateHook(behaviorsDir string, eval HookEval) (HookEvalResult, error) {
+	source, err := resolveBehavior(behaviorsDir, eval.Script)
+	if err != nil {
+		return HookEvalResult{}, err
+	}
+
+	var output []string
+	env := threadEnv{
+		owner:    scriptOwner{clientId: eval.Request.ClientId, group: eval.Request.Group},
+		output:   func(line string) { output = append(output, line) },
+		maxSteps: maxEvalSteps,
+	}
+	behavior, err := newStarlarkClientBehavior(source, 1, nil, env)
+	if err != nil {
+		return HookEvalResult{}, err
+	}
+	defer behavior.Close()
+
+	var hook starlark.Callable
+	switch eval.Hook {
+	case "on_request":
+		hook = behavior.onRequest
+	case "on_response":
+		hook = behavior.onResponse
+	case "on_error":
+		hook = behavior.onError
+	case "on_fail":
+		hook = behavior.onFail
+	case "on_retry":
+		hook = behavior.onRetry
+	default:
+		return HookEvalResult{}, fmt.Errorf("unknown hook '%s'", eval.Hook)
+	}
+	if hook == nil {
+		return HookEvalResult{}, fmt.Errorf("hook '%s' is not defined in the script", eval.Hook)
+	}
+
+	req := eval.Request
+	if req.Timestamp.IsZero() {
+		req.Timestamp = time.Now()
+	}
+	req.Meta, err = metaToStarlark(eval.Meta)
+	if err != nil {
+		return HookEvalResult{}, fmt.Errorf("invalid meta: %v", err)
+	}
+
+	resp := eval.Response
+	if resp == nil {
+		resp = &Response{}
+	}
+	var rerr error
+	if eval.Error != "" {
+		rerr = fmt.Errorf("%s", eval.Error)
+	}
+
+	result := HookEvalResult{Allow: true}
+	var hookErr error
+	switch eval.Hook {
+	case "on_request":
+		result.Allow, result.DelayMs, result.TimeoutMs, hookErr = behavior.OnRequest(&req)
+	case "on_response":
+		hookErr = behavior.OnResponse(&req, resp)
+	case "on_error":
+		hookErr = behavior.OnError(&req, resp)
+	case "on_fail":
+		hookErr = behavior.OnFail(&req, resp, rerr)
+	case "on_retry":
+		result.Allow, result.DelayMs, hookErr = behavior.OnRetry(&req, resp, rerr)
+	}
+
+	if hookErr != nil {
+		result.Error = hookErr.Error()
+	}
+	result.Meta, err = metaFromStarlark(req.Meta)
+	if err != nil && result.Error == "" {
+		result.Error = fmt.Sprintf("cannot convert meta: %v", err)
+	}
+	result.IdempotencyKey = req.IdempotencyKey
+	result.TraceId = req.TraceId
+	result.Output = output
+
+	return result, nil
+}
+
+// ParseOutcome converts outcome name, as seen by behavior scripts, back to Outcome
+func ParseOutcome(name string) Outcome {
+	switch strings.ToLower(name) {
+	case "success":
+		return OutcomeSuccess
+	case "server_error":
+		return OutcomeServerError
+	case "rejected":
+		return OutcomeRejected
+	case "timeout":
+		return OutcomeTimeout
+	case "dropped":
+		return OutcomeDropped
+	case "stale":
+		return OutcomeStale
+	default:
+		return OutcomeUnknown
+	}
+}
+
+// metaToStarlark converts JSON-like metadata to Starlark dict, using Starlark json module
+func metaToStarlark(meta map[string]any) (*starlark.Dict, error) {
+	if len(meta) == 0 {
+		return starlark.NewDict(0), nil
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	thread := &starlark.Thread{Name: "eval-meta"}
+	value, err := starlark.Call(thread, starlarkjson.Module.Members["decode"], starlark.Tuple{starlark.String(data)}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("meta must be an object")
+	}
+	return dict, nil
+}
+
+// metaFromStarlark converts Starlark dict to JSON-like metadata, using Starlark json module
+func metaFromStarlark(meta *starlark.Dict) (map[string]any, error) {
+	if meta == nil {
+		return nil, nil
+	}
+
+	thread := &starlark.Thread{Name: "eval-meta"}
+	value, err := starlark.Call(thread, starlarkjson.Module.Members["encode"], starlark.Tuple{meta}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal([]byte(value.(starlark.String)), &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
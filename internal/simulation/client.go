@@ -2,9 +2,12 @@ package simulation
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"math/rand"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -21,35 +24,108 @@ type Client struct {
 	metrics     *Metrics
 	running     atomic.Bool
 	requestRate time.Duration
+	rng         *rand.Rand // Client's own random generator for jitter, used only by the client loop
 	ctx         context.Context
 	cancel      context.CancelFunc
 	wg          sync.WaitGroup
 	behavior    ClientBehavior
 	mu          sync.RWMutex
+
+	behaviorStatus BehaviorStatus // Behavior script the client was created with, and whether it compiled
+
+	firstRequestFailureRate float64     // Probability that the very first request fails to connect
+	firstRequestSent        atomic.Bool // Set once the very first request is attempted
+	retryPolicy             RetryPolicy // Used if behavior has no retry hook
+
+	errorCooldown time.Duration // Pause of the client loop after a failed request
+	cooldownUntil atomic.Int64  // Unix time (ns) until which the client loop does not schedule new requests
+
+	adaptiveRate *rateController // AIMD control of request rate, nil if disabled
+	batchSize    int             // Number of logical operations batched into one request
+	arrivalModel ArrivalModel    // Distribution of intervals between requests
+	payloadSizes []PayloadSize   // Distribution of request body sizes (empty uses size of request data)
+	requestKinds []RequestKind   // Weighted kinds of requests (empty sends requests without kind)
+
+	workloadModel WorkloadModel // Open workload sends at request rate, closed waits for outcome and thinks
+	thinkTime     time.Duration // Time between outcome of a request and the next request of closed workload
+
+	timeout          time.Duration // Default request timeout, if behavior does not set one (0 waits indefinitely)
+	timeoutJitterPct float64       // Jitter of default request timeout in percent, sampled per request
+
+	abandonAfterFailures int          // Consecutive failed requests after which client abandons its session (0 disables)
+	consecutiveFailures  atomic.Int64 // Requests failed in a row, after all retries
+	abandoned            atomic.Bool  // Client gave up and stopped scheduling new requests
+
+	timeline *Timeline // Captures request arrivals of the run (nil disables)
+
+	paused *atomic.Bool // Simulation is paused, client loop does not schedule new requests (nil is never paused)
+
+	schedulingCtx  context.Context    // Cancelled to stop client loop from scheduling new requests, child of ctx
+	stopScheduling context.CancelFunc // Stops client loop, leaving in-flight requests running
+	closeBehavior  sync.Once          // Behavior is closed once, by whichever of stop and drain comes first
 }
 
-// NewClient creates a new client with the specified parameters
-// Accepts an optional behavior string. If empty, uses the default.
-func NewClient(id string, group string, network *Network, metrics *Metrics, behaviorScript string) *Client {
+// ErrClientTimeout is returned for a request the client gave up waiting for, after request timeout
+var ErrClientTimeout = errors.New("client request timed out")
+
+// minThinkTime is the shortest pause between requests of closed workload client,
+// so that requests completing instantly (denied by behavior, failed right away) do not spin the client loop
+const minThinkTime = time.Millisecond
+
+// pausePollInterval is how often paused client loop checks whether simulation was resumed
+const pausePollInterval = 50 * time.Millisecond
+
+// NewClient creates a new client of the group given by config, with behavior from config's resolved behavior source
+// (empty uses the default). Seed initializes the client's own random generator for jitter.
+// Script queue size limits hook calls waiting for the behavior script executor.
+// Script pool executes behavior hooks if given, otherwise behavior has its own executor goroutine.
+func NewClient(id string, network *Network, metrics *Metrics, config ClientConfig, seed int64, scriptQueueSize int, scriptPool *ScriptPool) *Client {
 	var behavior ClientBehavior
+	var behaviorStatus BehaviorStatus
 
-	if len(strings.TrimSpace(behaviorScript)) == 0 {
+	if len(strings.TrimSpace(config.BehaviorSource)) == 0 {
 		behavior = NewNoopClientBehavior()
 	} else {
 		var err error
-		behavior, err = NewStarlarkClientBehavior(behaviorScript)
+		behaviorStatus.Source = config.BehaviorSource
+		behaviorStatus.Hash = behaviorHash(config.BehaviorSource)
+		behavior, err = NewStarlarkClientBehavior(config.BehaviorSource, scriptQueueSize, scriptPool, id, config.Id)
 		if err != nil {
 			log.Printf("Error evaluating client behavior: %v", err)
 			behavior = NewNoopClientBehavior()
+			behaviorStatus.Error = err.Error()
+		} else {
+			behaviorStatus.Compiled = true
 		}
 	}
 
 	return &Client{
 		id:       id,
-		group:    group,
+		group:    config.Id,
 		network:  network,
 		metrics:  metrics,
+		rng:      rand.New(rand.NewSource(seed)),
 		behavior: behavior,
+
+		behaviorStatus: behaviorStatus,
+
+		firstRequestFailureRate: config.FirstRequestFailureRate,
+		retryPolicy:             config.RetryPolicy,
+
+		errorCooldown: config.ErrorCooldown,
+		adaptiveRate:  newRateController(config.AdaptiveRate),
+		batchSize:     max(config.BatchSize, 1),
+		arrivalModel:  config.ArrivalModel,
+		payloadSizes:  config.PayloadSizes,
+		requestKinds:  config.RequestKinds,
+
+		workloadModel: config.WorkloadModel,
+		thinkTime:     config.ThinkTime,
+
+		timeout:          config.Timeout,
+		timeoutJitterPct: config.TimeoutJitterPct,
+
+		abandonAfterFailures: config.AbandonAfterFailures,
 	}
 }
 
@@ -77,52 +153,238 @@ func (c *Client) Start(simulationCtx context.Context, requestRate time.Duration)
 	}
 
 	c.ctx, c.cancel = context.WithCancel(simulationCtx)
+	c.schedulingCtx, c.stopScheduling = context.WithCancel(c.ctx)
 	c.requestRate = requestRate
+	if c.adaptiveRate != nil {
+		c.adaptiveRate.reset(requestRate)
+	}
 
 	c.wg.Go(c.runWithJitter)
 }
 
-// Stop halts the client's request sending
+// StartReplay begins sending recorded requests at their offsets from the simulation start, instead of at the request rate
+func (c *Client) StartReplay(simulationCtx context.Context, startTime time.Time, requests []TimelineRequest) {
+	if !c.running.CompareAndSwap(false, true) {
+		return
+	}
+
+	c.ctx, c.cancel = context.WithCancel(simulationCtx)
+	c.schedulingCtx, c.stopScheduling = context.WithCancel(c.ctx)
+
+	c.wg.Go(func() { c.runReplay(startTime, requests) })
+}
+
+// Stop halts the client's request sending, aborting in-flight requests
 func (c *Client) Stop() {
 	c.cancel()
-	c.behavior.Close()
+	c.closeBehavior.Do(c.GetBehavior().Close)
 	c.wg.Wait()
 	c.running.Store(false)
 }
 
-// runWithJitter is the main client loop that sends requests at the specified rate with jitter
+// Drain stops the client loop from scheduling new requests, waits up to timeout for in-flight requests to complete,
+// including their retries, then aborts the rest, closes the behavior and halts the client,
+// returns whether all in-flight requests completed in time
+func (c *Client) Drain(timeout time.Duration) bool {
+	c.stopScheduling()
+
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+
+	drained := true
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		// Request is stuck (no timeout, black-holed, endless retries), abort it like Stop does
+		drained = false
+		c.cancel()
+		c.closeBehavior.Do(c.GetBehavior().Close)
+		<-done
+	}
+
+	c.closeBehavior.Do(c.GetBehavior().Close)
+	c.cancel()
+	c.running.Store(false)
+	return drained
+}
+
+// runWithJitter is the main client loop that sends requests at the specified rate with jitter,
+// or, for closed workload, sends requests one by one with think time in between
 func (c *Client) runWithJitter() {
 	c.metrics.AddActiveClient(c.group)
 	defer c.metrics.RemoveActiveClient(c.group)
+	c.metrics.startClientActivity(c.id)
+	defer c.metrics.stopClientActivity(c.id)
 	defer c.running.Store(false)
 
 	for {
 		select {
-		case <-c.ctx.Done():
+		case <-c.schedulingCtx.Done():
 			return
 		default:
 		}
 
-		// Schedule request
-		c.wg.Go(func() {
-			req := &Request{
-				Id:        fmt.Sprintf("%s-%d", c.id, time.Now().UnixNano()),
-				ClientId:  c.id,
-				Data:      "test data",
-				Timestamp: time.Now(),
-				Meta:      starlark.NewDict(0), // Initialize empty dict for starlark metadata to save between hooks calls
+		// Simulation is paused, wait without scheduling requests, in-flight requests complete normally
+		if c.paused != nil && c.paused.Load() {
+			SleepWithContext(c.schedulingCtx, pausePollInterval)
+			continue
+		}
 
-			}
-			c.requestWithHooks(req)
-		})
+		// Client is cooling down after a failed request, wait and check again (cooldown could be extended meanwhile)
+		if wait := time.Until(time.Unix(0, c.cooldownUntil.Load())); wait > 0 {
+			SleepWithContext(c.schedulingCtx, wait)
+			continue
+		}
 
-		// Calculate next interval with jitter
+		// Schedule request, closed workload waits for its outcome
+		arrival := c.nextArrival()
+		if c.workloadModel == WorkloadClosed {
+			c.recordArrival(arrival)
+			c.send(arrival)
+		} else {
+			c.dispatch(arrival)
+		}
+
+		// Calculate next interval with jitter, batched request is sent once per batch of logical operations
 		jitterPercent := 0.2 // 20% jitter
-		jitter := time.Duration(float64(c.requestRate) * jitterPercent * (rand.Float64()*2 - 1))
-		nextInterval := c.requestRate + jitter
+		requestRate := c.requestRate
+		if c.adaptiveRate != nil {
+			requestRate = c.adaptiveRate.interval()
+		}
+		requestRate *= time.Duration(c.batchSize)
+		if c.workloadModel == WorkloadClosed {
+			requestRate = c.thinkTime
+		}
+		var nextInterval time.Duration
+		if c.arrivalModel == ArrivalPoisson {
+			// Exponentially distributed interval (-rate * ln(1-rand)), jitter does not apply
+			nextInterval = time.Duration(c.rng.ExpFloat64() * float64(requestRate))
+		} else {
+			jitter := time.Duration(float64(requestRate) * jitterPercent * (c.rng.Float64()*2 - 1))
+			nextInterval = requestRate + jitter
+		}
+		if c.workloadModel == WorkloadClosed {
+			nextInterval = max(nextInterval, minThinkTime)
+		}
+
+		SleepWithContext(c.schedulingCtx, nextInterval)
+	}
+}
+
+// runReplay is the client loop that sends recorded requests at their offsets, ignoring request rate and error cooldown,
+// time the simulation spent paused shifts the remaining offsets
+func (c *Client) runReplay(startTime time.Time, requests []TimelineRequest) {
+	c.metrics.AddActiveClient(c.group)
+	defer c.metrics.RemoveActiveClient(c.group)
+	c.metrics.startClientActivity(c.id)
+	defer c.metrics.stopClientActivity(c.id)
+	defer c.running.Store(false)
+
+	var pausedFor time.Duration
+	for _, recorded := range requests {
+		for {
+			if c.schedulingCtx.Err() != nil {
+				return
+			}
+
+			// Simulation is paused, wait without sending requests, in-flight requests complete normally
+			if c.paused != nil && c.paused.Load() {
+				pausedAt := time.Now()
+				SleepWithContext(c.schedulingCtx, pausePollInterval)
+				pausedFor += time.Since(pausedAt)
+				continue
+			}
+
+			// Sleep until the offset in steps, so that pause is noticed before the request is due
+			wait := time.Until(startTime.Add(recorded.Offset + pausedFor))
+			if wait <= 0 {
+				break
+			}
+			SleepWithContext(c.schedulingCtx, min(wait, pausePollInterval))
+		}
+
+		c.dispatch(recorded)
+	}
+}
+
+// nextArrival samples trace id, payload size and kind of the next request
+func (c *Client) nextArrival() TimelineRequest {
+	arrival := TimelineRequest{
+		ClientId: c.id,
+		Group:    c.group,
+		TraceId:  NewTraceId(c.rng),
+	}
+	if i := sampleByWeight(c.rng, c.payloadSizes, func(ps PayloadSize) float64 { return ps.Weight }); i >= 0 {
+		arrival.SizeBytes = c.payloadSizes[i].SizeBytes
+	}
+	if i := sampleByWeight(c.rng, c.requestKinds, func(rk RequestKind) float64 { return rk.Weight }); i >= 0 {
+		arrival.Kind = c.requestKinds[i].Name
+	}
+	return arrival
+}
 
-		SleepWithContext(c.ctx, nextInterval)
+// sampleByWeight picks index of an item with probability proportional to its weight (negative weights count as zero),
+// returns -1 if there are no items with positive weight
+func sampleByWeight[T any](rng *rand.Rand, items []T, weight func(T) float64) int {
+	var total float64
+	for _, item := range items {
+		total += max(weight(item), 0)
 	}
+	if total <= 0 {
+		return -1
+	}
+
+	r := rng.Float64() * total
+	for i, item := range items {
+		r -= max(weight(item), 0)
+		if r < 0 {
+			return i
+		}
+	}
+	return len(items) - 1
+}
+
+// dispatch sends a new request in background, with behavior hooks and retries
+func (c *Client) dispatch(arrival TimelineRequest) {
+	c.recordArrival(arrival)
+	c.wg.Go(func() { c.send(arrival) })
+}
+
+// recordArrival captures request arrival in the run timeline, if capture is enabled
+func (c *Client) recordArrival(arrival TimelineRequest) {
+	if c.timeline != nil {
+		c.timeline.record(arrival)
+	}
+}
+
+// send creates a new request for the arrival and sends it with behavior hooks and retries, waiting for the outcome,
+// payload size is the body size of one logical operation (0 uses size of request data)
+func (c *Client) send(arrival TimelineRequest) {
+	req := &Request{
+		Id:        fmt.Sprintf("%s-%d", c.id, time.Now().UnixNano()),
+		ClientId:  c.id,
+		Group:     c.group,
+		TraceId:   arrival.TraceId,
+		Data:      "test data",
+		Timestamp: time.Now(),
+		Meta:      starlark.NewDict(0), // Initialize empty dict for starlark metadata to save between hooks calls
+		BatchSize: c.batchSize,
+		Kind:      arrival.Kind,
+	}
+	if i := slices.IndexFunc(c.requestKinds, func(rk RequestKind) bool { return rk.Name == arrival.Kind }); i >= 0 {
+		if c.requestKinds[i].Data != "" {
+			req.Data = c.requestKinds[i].Data
+		}
+		req.CPUCost = c.requestKinds[i].CPUCost
+	}
+	req.SizeBytes = len(req.Data) * c.batchSize
+	if arrival.SizeBytes > 0 {
+		req.SizeBytes = arrival.SizeBytes * c.batchSize
+	}
+	c.requestWithHooks(req)
 }
 
 // requestWithHooks sends a single request with retry logic (non-recursive)
@@ -132,6 +394,8 @@ func (c *Client) requestWithHooks(req *Request) {
 	c.mu.RUnlock()
 
 	isRetry := false
+	attempts := 0
+	lastRetryDelayMs := 0
 	var timeout time.Duration = 0
 
 	for {
@@ -139,12 +403,12 @@ func (c *Client) requestWithHooks(req *Request) {
 		for {
 			allow, delayMs, timeoutMs, err := behavior.OnRequest(req)
 			if err != nil {
-				log.Printf("Error evaluating client behavior: %v", err)
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, err)
 			}
 
 			// Request blocked by client behavior
 			if !allow {
-				c.metrics.ClientBlockedRequests.Add(1)
+				c.metrics.count(&c.metrics.ClientBlockedRequests)
 				return
 			}
 
@@ -165,60 +429,97 @@ func (c *Client) requestWithHooks(req *Request) {
 			break // Allowed, proceed to send
 		}
 
-		c.metrics.ClientSentRequests.Add(1)
-		if isRetry {
-			c.metrics.ClientRetryRequests.Add(1)
+		attempts++
+		req.IsRetry = isRetry
+		// Outcome is counted only if the attempt was, so that recording resumed mid-request does not break counters balance
+		counted := c.metrics.count(&c.metrics.ClientSentRequests)
+		c.metrics.recordClientSent(c.id)
+		if isRetry && counted {
+			c.metrics.count(&c.metrics.ClientRetryRequests)
 		}
 
+		var resp Response
+		var err error
 		start := time.Now()
-		resp, err := c.sendRequest(req, timeout)
+		if !isRetry && c.failFirstRequest() {
+			resp, err = Response{Outcome: OutcomeDropped}, fmt.Errorf("connection failed")
+		} else {
+			attemptTimeout := timeout
+			if attemptTimeout <= 0 {
+				attemptTimeout = c.defaultTimeout()
+			}
+			resp, err = c.sendRequest(req, attemptTimeout)
+		}
 		responseTime := time.Since(start)
 
 		c.metrics.recordResponseTime(responseTime)
+		c.metrics.recordResponseOutcome(resp.Outcome, req.Kind)
+		c.metrics.recordGoodput(resp.Outcome, responseTime)
+		c.metrics.recordSLO(responseTime)
+		if c.adaptiveRate != nil {
+			c.adaptiveRate.observe(start, resp.Outcome, responseTime)
+		}
 
 		var shouldRetry bool
 		var retryDelayMs int
 
-		if err == nil {
-			if resp.Ok {
-				c.metrics.ClientSuccessResponses.Add(1)
+		switch resp.Outcome {
+		case OutcomeSuccess, OutcomeStale:
+			if counted {
+				c.metrics.recordClientSuccess()
+			}
 
-				berr := behavior.OnResponse(req, &resp)
-				if berr != nil {
-					log.Printf("Error evaluating client behavior: %v", berr)
-				}
+			berr := behavior.OnResponse(req, &resp)
+			if berr != nil {
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, berr)
+			}
 
-				// Successful response, no retry needed
-				return
-			} else {
-				c.metrics.ClientErrorResponses.Add(1)
+			// Successful response, no retry needed
+			c.metrics.recordAttempts(attempts)
+			c.consecutiveFailures.Store(0)
+			return
 
-				berr := behavior.OnError(req, &resp)
-				if berr != nil {
-					log.Printf("Error evaluating client behavior: %v", berr)
-				}
+		case OutcomeServerError, OutcomeRejected:
+			if counted {
+				c.metrics.count(&c.metrics.ClientErrorResponses)
+			}
+			c.startCooldown()
 
-				shouldRetry, retryDelayMs, berr = behavior.OnRetry(req, &resp, nil)
-				if berr != nil {
-					log.Printf("Error evaluating client behavior: %v", berr)
-				}
+			berr := behavior.OnError(req, &resp)
+			if berr != nil {
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, berr)
 			}
-		} else {
-			c.metrics.NetworkFailedRequests.Add(1)
 
-			berr := behavior.OnFail(req, err)
+			shouldRetry, retryDelayMs, berr = c.onRetry(behavior, req, &resp, nil, attempts, lastRetryDelayMs)
 			if berr != nil {
-				log.Printf("Error evaluating client behavior: %v", berr)
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, berr)
+			}
+
+		default:
+			// Timeout, dropped in the network or cancelled, there is no response from server,
+			// but retry hook still gets outcome of the attempt
+			if counted && errors.Is(err, ErrClientTimeout) {
+				c.metrics.count(&c.metrics.ClientTimeoutRequests) // Client gave up, not a network failure
+			} else if counted {
+				c.metrics.count(&c.metrics.NetworkFailedRequests)
 			}
+			c.startCooldown()
 
-			shouldRetry, retryDelayMs, berr = behavior.OnRetry(req, nil, err)
+			berr := behavior.OnFail(req, &resp, err)
 			if berr != nil {
-				log.Printf("Error evaluating client behavior: %v", berr)
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, berr)
+			}
+
+			shouldRetry, retryDelayMs, berr = c.onRetry(behavior, req, &resp, err, attempts, lastRetryDelayMs)
+			if berr != nil {
+				log.Printf("Error evaluating client behavior (trace %s): %v", req.TraceId, berr)
 			}
 		}
 
 		// Handle retry - exceptional case that requires another attempt
 		if shouldRetry {
+			lastRetryDelayMs = retryDelayMs
+
 			// Apply retry delay if specified
 			if retryDelayMs > 0 {
 				err := SleepWithContext(c.ctx, time.Duration(retryDelayMs)*time.Millisecond)
@@ -232,19 +533,121 @@ func (c *Client) requestWithHooks(req *Request) {
 		}
 
 		// Normal completion - request finished successfully or no retry needed
+		c.metrics.recordAttempts(attempts)
+		c.recordFailure()
 		break
 	}
 }
 
-// sendRequest sends a request and waits for a response up to the client's requestTimeout
+// onRetry asks behavior whether to retry the request, or decides by built-in retry policy if behavior has no retry hook,
+// lastDelayMs is the delay before the previous retry of the request (0 if none)
+func (c *Client) onRetry(behavior ClientBehavior, req *Request, resp *Response, rerr error, attempts int, lastDelayMs int) (allow bool, delayMs int, err error) {
+	policy := c.retryPolicy
+	if behavior.HasRetryHook() || (!policy.RetryOnServerError && !policy.RetryOnNetworkError) {
+		return behavior.OnRetry(req, resp, rerr)
+	}
+
+	maxRetries := policy.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = DefaultMaxRetries
+	}
+	if attempts > maxRetries {
+		return false, 0, nil
+	}
+
+	switch resp.Outcome {
+	case OutcomeServerError, OutcomeRejected:
+		allow = policy.RetryOnServerError
+	case OutcomeTimeout, OutcomeDropped:
+		allow = policy.RetryOnNetworkError
+	}
+
+	delay := policy.retryDelay(attempts, time.Duration(lastDelayMs)*time.Millisecond)
+	return allow, int(delay.Milliseconds()), nil
+}
+
+// retryDelay returns delay before the retry after the given number of attempts, by the jitter mode
+func (p RetryPolicy) retryDelay(attempts int, lastDelay time.Duration) time.Duration {
+	base := p.RetryDelay
+	if p.JitterMode == RetryJitterNone || base <= 0 {
+		return base
+	}
+
+	capDelay := func(d float64) time.Duration {
+		if p.MaxRetryDelay > 0 {
+			d = min(d, float64(p.MaxRetryDelay))
+		}
+		return time.Duration(d)
+	}
+
+	if p.JitterMode == RetryJitterDecorrelated {
+		lastDelay = max(lastDelay, base)
+		return capDelay(float64(base) + rand.Float64()*float64(3*lastDelay-base))
+	}
+
+	// Exponential backoff, growth is limited so that it does not overflow
+	backoff := capDelay(float64(base) * math.Pow(2, float64(min(attempts-1, 30))))
+
+	if p.JitterMode == RetryJitterEqual {
+		return backoff/2 + time.Duration(rand.Float64()*float64(backoff/2))
+	}
+	return time.Duration(rand.Float64() * float64(backoff)) // Full jitter
+}
+
+// defaultTimeout samples the client group's default request timeout with jitter, 0 if there is no default timeout
+func (c *Client) defaultTimeout() time.Duration {
+	if c.timeout <= 0 || c.timeoutJitterPct <= 0 {
+		return c.timeout
+	}
+	jitter := c.timeoutJitterPct / 100 * (rand.Float64()*2 - 1)
+	return max(time.Duration(float64(c.timeout)*(1+jitter)), time.Millisecond)
+}
+
+// recordFailure counts failed request towards consecutive failures, and abandons client session when there are too many
+func (c *Client) recordFailure() {
+	failures := c.consecutiveFailures.Add(1)
+	if c.abandonAfterFailures <= 0 || failures < int64(c.abandonAfterFailures) {
+		return
+	}
+	if c.abandoned.CompareAndSwap(false, true) {
+		c.metrics.count(&c.metrics.ClientAbandoned)
+		c.stopScheduling() // In-flight requests complete, client is stopped with the simulation
+	}
+}
+
+// startCooldown pauses scheduling of new requests for the error cooldown after a failed request
+func (c *Client) startCooldown() {
+	if c.errorCooldown > 0 {
+		c.cooldownUntil.Store(time.Now().Add(c.errorCooldown).UnixNano())
+	}
+}
+
+// establishConnection marks client connection as already established (warm), so its first request can not fail to connect
+func (c *Client) establishConnection() {
+	c.firstRequestSent.Store(true)
+}
+
+// failFirstRequest decides whether the client's very first request fails to connect (cold connection)
+func (c *Client) failFirstRequest() bool {
+	if !c.firstRequestSent.CompareAndSwap(false, true) {
+		return false
+	}
+	return c.firstRequestFailureRate > 0 && rand.Float64() < c.firstRequestFailureRate
+}
+
+// sendRequest sends a request and waits for a response up to the client's requestTimeout,
+// the attempt is cancelled when the client gives up, so that requests hanging in the network do not outlive it
 func (c *Client) sendRequest(req *Request, timeout time.Duration) (Response, error) {
 	resultCh := make(chan struct {
 		resp Response
 		err  error
 	}, 1)
 
+	attemptCtx, cancelAttempt := context.WithCancel(c.ctx)
+	defer cancelAttempt()
+
 	go func() {
-		resp, err := c.network.Send(c.ctx, *req)
+		resp, err := c.network.Send(attemptCtx, *req)
 		resultCh <- struct {
 			resp Response
 			err  error
@@ -258,7 +661,7 @@ func (c *Client) sendRequest(req *Request, timeout time.Duration) (Response, err
 		case <-c.ctx.Done():
 			return Response{}, c.ctx.Err()
 		case <-time.After(timeout):
-			return Response{}, fmt.Errorf("client request timed")
+			return Response{Outcome: OutcomeTimeout}, ErrClientTimeout
 		}
 	} else {
 		select {
@@ -6,9 +6,11 @@ import (
 	"math"
 	"math/rand"
 	"strings"
+	"sync/atomic"
 	"time"
 	"unsafe"
 
+	starlarkjson "go.starlark.net/lib/json"
 	"go.starlark.net/starlark"
 	"go.starlark.net/syntax"
 )
@@ -17,11 +19,16 @@ type ClientBehavior interface {
 	OnRequest(req *Request) (allow bool, delayMs int, timeoutMs int, err error)
 	OnResponse(req *Request, resp *Response) error
 	OnError(req *Request, resp *Response) error
-	OnFail(req *Request, rerr error) error
+	OnFail(req *Request, resp *Response, rerr error) error
 	OnRetry(req *Request, resp *Response, rerr error) (allow bool, delayMs int, err error)
+	HasRetryHook() bool
 	Close()
 }
 
+// maxHookSteps limits execution of each pooled client hook call, so that a looping script fails the call
+// instead of taking a shared pool worker for good
+const maxHookSteps = 10_000_000
+
 type executionType int
 
 const (
@@ -60,25 +67,88 @@ type StarlarkClientBehavior struct {
 
 	executionChan chan *scriptExecution
 	stopChan      chan struct{}
+
+	env threadEnv // Applied to the compiler and executor threads
+
+	thread    *starlark.Thread // Executor thread, holds script state and random source between hook calls
+	pool      *ScriptPool      // Shared executor pool, nil if behavior has its own executor goroutine
+	scheduled atomic.Bool      // Set while behavior is queued or being executed in the pool
 }
 
+// DefaultScriptQueueSize is the default number of hook calls which can wait for the script executor
+const DefaultScriptQueueSize = 100
+
 const randSourceLocalKey = "starlark_random_source"
 const threadStateKey = "starlark_thread_state"
+const threadOwnerKey = "starlark_thread_owner"
+const threadOutputKey = "starlark_thread_output"
+const circuitBreakersLocalKey = "starlark_circuit_breakers"
+
+// scriptOwner identifies the client which runs the script, stored in executor thread local
+type scriptOwner struct {
+	clientId string
+	group    string
+}
+
+// threadEnv is the environment of script threads
+type threadEnv struct {
+	owner    scriptOwner  // Owning client, prefixes log() messages (empty if none)
+	output   func(string) // Receives print() and log() output instead of stdout and log (nil if not captured)
+	maxSteps uint64       // Limits execution steps of each thread (0 is unlimited)
+}
+
+// setup applies environment to the thread
+func (env threadEnv) setup(thread *starlark.Thread) {
+	if env.owner.clientId != "" {
+		thread.SetLocal(threadOwnerKey, env.owner)
+	}
+	if env.output != nil {
+		thread.SetLocal(threadOutputKey, env.output)
+	}
+	if env.maxSteps > 0 {
+		thread.SetMaxExecutionSteps(env.maxSteps)
+	}
+}
 
 var (
 	globalStarlarkBuiltins = starlark.StringDict{
 		"get_state": starlark.NewBuiltin("get_state", starlarkState),
+		"log":       starlark.NewBuiltin("log", starlarkLog),
 		"now":       starlark.NewBuiltin("now", starlarkNow),
 		"pow":       starlark.NewBuiltin("pow", starlarkPow),
 		"print":     starlark.NewBuiltin("print", starlarkPrint),
 		"round":     starlark.NewBuiltin("round", starlarkRound),
 		"random":    starlark.NewBuiltin("random", starlarkRandom),
+
+		"random_exp":    starlark.NewBuiltin("random_exp", starlarkRandomExp),
+		"random_normal": starlark.NewBuiltin("random_normal", starlarkRandomNormal),
+		"random_int":    starlark.NewBuiltin("random_int", starlarkRandomInt),
+
+		// min, max and abs are Starlark universe builtins already
+		"clamp": starlark.NewBuiltin("clamp", starlarkClamp),
+
+		"circuit_breaker": starlark.NewBuiltin("circuit_breaker", starlarkCircuitBreaker),
+		"json_encode":     starlark.NewBuiltin("json_encode", starlarkJSONEncode),
+		"json_decode":     starlark.NewBuiltin("json_decode", starlarkJSONDecode),
 	}
 )
 
-// NewStarlarkClientBehavior loads the Starlark script and extracts handler functions
-func NewStarlarkClientBehavior(script string) (*StarlarkClientBehavior, error) {
+// NewStarlarkClientBehavior loads the Starlark script and extracts handler functions,
+// queueSize is the number of hook calls which can wait for the script executor (DefaultScriptQueueSize if not positive),
+// hooks are executed by the pool if given, otherwise by the behavior's own executor goroutine,
+// clientId and group of the owning client prefix messages of log() builtin
+func NewStarlarkClientBehavior(script string, queueSize int, pool *ScriptPool, clientId, group string) (*StarlarkClientBehavior, error) {
+	return newStarlarkClientBehavior(script, queueSize, pool, threadEnv{owner: scriptOwner{clientId: clientId, group: group}})
+}
+
+// newStarlarkClientBehavior loads the Starlark script like NewStarlarkClientBehavior, running its threads in the given environment
+func newStarlarkClientBehavior(script string, queueSize int, pool *ScriptPool, env threadEnv) (*StarlarkClientBehavior, error) {
+	if queueSize <= 0 {
+		queueSize = DefaultScriptQueueSize
+	}
+
 	thread := &starlark.Thread{Name: "compiler"}
+	env.setup(thread)
 	options := &syntax.FileOptions{}
 
 	globals, err := starlark.ExecFileOptions(options, thread, "client_behavior.star", script, globalStarlarkBuiltins)
@@ -103,42 +173,96 @@ func NewStarlarkClientBehavior(script string) (*StarlarkClientBehavior, error) {
 		onError:       getFn("on_error"),
 		onFail:        getFn("on_fail"),
 		onRetry:       getFn("on_retry"),
-		executionChan: make(chan *scriptExecution, 10000), // Buffer for requests
+		executionChan: make(chan *scriptExecution, queueSize), // Buffer for requests
 		stopChan:      make(chan struct{}),
+		pool:          pool,
+		env:           env,
 	}
 
-	// Start the single executor goroutine
-	go behavior.scriptExecutor()
+	// Start the single executor goroutine, unless hooks are executed by the shared pool
+	if pool == nil {
+		go behavior.scriptExecutor()
+	}
 
 	return behavior, nil
 }
 
 func (b *StarlarkClientBehavior) scriptExecutor() {
-	thread := &starlark.Thread{Name: "executor"}
+	for {
+		select {
+		case exec := <-b.executionChan:
+			result := b.executeFunction(b.executorThread(), exec)
+			exec.resultCh <- result
+
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+// executorThread returns the executor thread, creating it and initializing script state on the first hook call
+func (b *StarlarkClientBehavior) executorThread() *starlark.Thread {
+	if b.thread != nil {
+		return b.thread
+	}
+
+	b.thread = &starlark.Thread{Name: "executor"}
+	b.env.setup(b.thread)
 
 	// init "global" / thread local state for the script
 	if b.setState != nil {
-		stateValue, err := starlark.Call(thread, b.setState, nil, nil)
+		if b.pool != nil {
+			limitSteps(b.thread, maxHookSteps)
+		}
+		stateValue, err := starlark.Call(b.thread, b.setState, nil, nil)
 		if err != nil {
 			log.Printf("set_state error: %v\n", err)
 		} else {
-			thread.SetLocal(threadStateKey, stateValue)
+			b.thread.SetLocal(threadStateKey, stateValue)
 			// log.Printf("Initial state stored in thread local: %v\n", stateValue)
 		}
 	}
 
+	return b.thread
+}
+
+// executePending executes hook calls queued so far, called by the pool worker which owns the behavior,
+// returns false if behavior is closed and remaining calls are abandoned
+func (b *StarlarkClientBehavior) executePending() bool {
 	for {
+		select {
+		case <-b.stopChan:
+			return false
+		default:
+		}
+
 		select {
 		case exec := <-b.executionChan:
+			thread := b.executorThread()
+			limitSteps(thread, maxHookSteps)
 			result := b.executeFunction(thread, exec)
 			exec.resultCh <- result
-
-		case <-b.stopChan:
-			return
+		default:
+			return true
 		}
 	}
 }
 
+// submit queues hook call for the executor, returns false if behavior is closed
+func (b *StarlarkClientBehavior) submit(exec *scriptExecution) bool {
+	select {
+	case b.executionChan <- exec:
+		// Successfully queued
+	case <-b.stopChan:
+		return false
+	}
+
+	if b.pool != nil {
+		b.pool.schedule(b)
+	}
+	return true
+}
+
 func (b *StarlarkClientBehavior) executeFunction(thread *starlark.Thread, exec *scriptExecution) scriptResult {
 	var result scriptResult
 
@@ -199,6 +323,7 @@ func (b *StarlarkClientBehavior) executeFunction(thread *starlark.Thread, exec *
 		}
 
 		reqDict := requestToDict(exec.req)
+		setCategory(reqDict, exec.resp)
 		errValue := errorToValue(exec.err)
 		args := starlark.Tuple{reqDict, errValue}
 		_, err := starlark.Call(thread, b.onFail, args, nil)
@@ -215,6 +340,7 @@ func (b *StarlarkClientBehavior) executeFunction(thread *starlark.Thread, exec *
 		}
 
 		reqDict := requestToDict(exec.req)
+		setCategory(reqDict, exec.resp)
 		respDict := responseToDict(exec.resp)
 		errValue := errorToValue(exec.err)
 		args := starlark.Tuple{reqDict, respDict, errValue}
@@ -236,6 +362,11 @@ func (b *StarlarkClientBehavior) Close() {
 	close(b.stopChan)
 }
 
+// HasRetryHook returns whether the script defines `on_retry` hook
+func (b *StarlarkClientBehavior) HasRetryHook() bool {
+	return b.onRetry != nil
+}
+
 // Call `on_request` hook
 func (b *StarlarkClientBehavior) OnRequest(req *Request) (allow bool, delayMs int, timeoutMs int, err error) {
 	resultCh := make(chan scriptResult, 1)
@@ -245,10 +376,7 @@ func (b *StarlarkClientBehavior) OnRequest(req *Request) (allow bool, delayMs in
 		resultCh: resultCh,
 	}
 
-	select {
-	case b.executionChan <- exec:
-		// Successfully queued
-	case <-b.stopChan:
+	if !b.submit(exec) {
 		return false, 0, 0, nil // fmt.Errorf("execution cancelled during shutdown")
 	}
 
@@ -270,10 +398,7 @@ func (b *StarlarkClientBehavior) OnResponse(req *Request, resp *Response) error
 		resultCh: resultCh,
 	}
 
-	select {
-	case b.executionChan <- exec:
-		// Successfully queued
-	case <-b.stopChan:
+	if !b.submit(exec) {
 		return nil // fmt.Errorf("execution cancelled during shutdown")
 	}
 
@@ -295,10 +420,7 @@ func (b *StarlarkClientBehavior) OnError(req *Request, resp *Response) error {
 		resultCh: resultCh,
 	}
 
-	select {
-	case b.executionChan <- exec:
-		// Successfully queued
-	case <-b.stopChan:
+	if !b.submit(exec) {
 		return nil // fmt.Errorf("execution cancelled during shutdown")
 	}
 
@@ -311,19 +433,17 @@ func (b *StarlarkClientBehavior) OnError(req *Request, resp *Response) error {
 }
 
 // Call `on_fail` hook
-func (b *StarlarkClientBehavior) OnFail(req *Request, rerr error) error {
+func (b *StarlarkClientBehavior) OnFail(req *Request, resp *Response, rerr error) error {
 	resultCh := make(chan scriptResult, 1)
 	exec := &scriptExecution{
 		execType: execOnFail,
 		req:      req,
+		resp:     resp,
 		err:      rerr,
 		resultCh: resultCh,
 	}
 
-	select {
-	case b.executionChan <- exec:
-		// Successfully queued
-	case <-b.stopChan:
+	if !b.submit(exec) {
 		return nil // fmt.Errorf("execution cancelled during shutdown")
 	}
 
@@ -346,10 +466,7 @@ func (b *StarlarkClientBehavior) OnRetry(req *Request, resp *Response, rerr erro
 		resultCh: resultCh,
 	}
 
-	select {
-	case b.executionChan <- exec:
-		// Successfully queued
-	case <-b.stopChan:
+	if !b.submit(exec) {
 		return false, 0, nil // fmt.Errorf("execution cancelled during shutdown")
 	}
 
@@ -434,10 +551,47 @@ func starlarkPrint(thread *starlark.Thread, fn *starlark.Builtin, args starlark.
 			parts = append(parts, arg.String())
 		}
 	}
+	if output, ok := thread.Local(threadOutputKey).(func(string)); ok {
+		output(strings.Join(parts, " "))
+		return starlark.None, nil
+	}
 	fmt.Println(strings.Join(parts, " "))
 	return starlark.None, nil
 }
 
+// starlarkLog implements log(level, msg) function, which writes message to the log prefixed with the owning client,
+// level is one of "info", "warn" or "error", unknown levels are logged as "info"
+func starlarkLog(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var level string
+	var msg starlark.Value
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 2, &level, &msg); err != nil {
+		return nil, err
+	}
+
+	switch level {
+	case "info", "warn", "error":
+	default:
+		level = "info"
+	}
+
+	text := msg.String()
+	if str, ok := msg.(starlark.String); ok {
+		text = string(str)
+	}
+
+	message := fmt.Sprintf("Behavior [%s]: %s", strings.ToUpper(level), text)
+	if owner, ok := thread.Local(threadOwnerKey).(scriptOwner); ok {
+		message = fmt.Sprintf("Behavior [%s] client %s (group %s): %s", strings.ToUpper(level), owner.clientId, owner.group, text)
+	}
+
+	if output, ok := thread.Local(threadOutputKey).(func(string)); ok {
+		output(message)
+		return starlark.None, nil
+	}
+	log.Print(message)
+	return starlark.None, nil
+}
+
 // Creates a round function
 // - round(number, ndigits=None) -> float or int
 // - If ndigits is omitted or None, returns the nearest integer as an int
@@ -502,9 +656,8 @@ func starlarkRound(thread *starlark.Thread, b *starlark.Builtin, args starlark.T
 	}
 }
 
-// starlarkRandom generates a random float between 0.0 (inclusive) and 1.0 (exclusive)
-func starlarkRandom(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
-	// Get or create the *rand.Rand instance for this Starlark thread
+// threadRand returns the *rand.Rand instance of the Starlark thread, creating it on first use
+func threadRand(thread *starlark.Thread) *rand.Rand {
 	randInst, ok := thread.Local(randSourceLocalKey).(*rand.Rand)
 	if !ok {
 		// Create a new thread-local random generator
@@ -513,8 +666,300 @@ func starlarkRandom(thread *starlark.Thread, fn *starlark.Builtin, args starlark
 		randInst = rand.New(rand.NewSource(seed))
 		thread.SetLocal(randSourceLocalKey, randInst)
 	}
+	return randInst
+}
 
-	return starlark.Float(randInst.Float64()), nil
+// starlarkRandom generates a random float between 0.0 (inclusive) and 1.0 (exclusive)
+func starlarkRandom(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	return starlark.Float(threadRand(thread).Float64()), nil
+}
+
+// starlarkRandomExp implements random_exp(mean), which returns exponentially distributed float with the given mean
+func starlarkRandomExp(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var meanArg starlark.Value
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "mean", &meanArg); err != nil {
+		return nil, err
+	}
+	mean, err := numberArg(fn, "mean", meanArg)
+	if err != nil {
+		return nil, err
+	}
+	if mean < 0 {
+		return nil, fmt.Errorf("%s: mean must not be negative, got %g", fn.Name(), mean)
+	}
+	return starlark.Float(threadRand(thread).ExpFloat64() * mean), nil
+}
+
+// starlarkRandomNormal implements random_normal(mean, stddev), which returns normally distributed float
+func starlarkRandomNormal(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var meanArg, stddevArg starlark.Value
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "mean", &meanArg, "stddev", &stddevArg); err != nil {
+		return nil, err
+	}
+	mean, err := numberArg(fn, "mean", meanArg)
+	if err != nil {
+		return nil, err
+	}
+	stddev, err := numberArg(fn, "stddev", stddevArg)
+	if err != nil {
+		return nil, err
+	}
+	if stddev < 0 {
+		return nil, fmt.Errorf("%s: stddev must not be negative, got %g", fn.Name(), stddev)
+	}
+	return starlark.Float(mean + threadRand(thread).NormFloat64()*stddev), nil
+}
+
+// numberArg converts int or float argument of the builtin to float64
+func numberArg(fn *starlark.Builtin, name string, v starlark.Value) (float64, error) {
+	switch v.(type) {
+	case starlark.Int, starlark.Float:
+		f, _ := starlark.AsFloat(v)
+		return f, nil
+	default:
+		return 0, fmt.Errorf("%s: %s must be int or float, got %s", fn.Name(), name, v.Type())
+	}
+}
+
+// starlarkRandomInt implements random_int(lo, hi), which returns uniformly distributed int between lo and hi inclusive
+func starlarkRandomInt(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var lo, hi int64
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "lo", &lo, "hi", &hi); err != nil {
+		return nil, err
+	}
+	if lo > hi {
+		return nil, fmt.Errorf("%s: lo %d is greater than hi %d", fn.Name(), lo, hi)
+	}
+	span := uint64(hi-lo) + 1
+	if span == 0 || span > math.MaxInt64 {
+		return nil, fmt.Errorf("%s: range from %d to %d is too large", fn.Name(), lo, hi)
+	}
+	return starlark.MakeInt64(lo + threadRand(thread).Int63n(int64(span))), nil
+}
+
+// starlarkJSONEncode implements json_encode(value), which returns JSON string of nested dicts, lists, strings,
+// numbers, bools and None, using Starlark json module (fails on other types)
+func starlarkJSONEncode(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var value starlark.Value
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &value); err != nil {
+		return nil, err
+	}
+	return callJSONModule(thread, fn, "encode", value)
+}
+
+// starlarkJSONDecode implements json_decode(string), which returns value of JSON string as dicts, lists and scalars,
+// using Starlark json module
+func starlarkJSONDecode(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var data string
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 1, &data); err != nil {
+		return nil, err
+	}
+	return callJSONModule(thread, fn, "decode", starlark.String(data))
+}
+
+// callJSONModule calls function of Starlark json module with the argument, reporting its errors under the builtin's name
+func callJSONModule(thread *starlark.Thread, fn *starlark.Builtin, name string, arg starlark.Value) (starlark.Value, error) {
+	member := starlarkjson.Module.Members[name].(*starlark.Builtin)
+	result, err := member.CallInternal(thread, starlark.Tuple{arg}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", fn.Name(), strings.TrimPrefix(err.Error(), member.Name()+": "))
+	}
+	return result, nil
+}
+
+// starlarkClamp implements clamp(x, lo, hi), which returns lo if x is below it, hi if x is above it and x otherwise,
+// the returned value keeps its type, like min and max do for mixed ints and floats
+func starlarkClamp(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x, lo, hi starlark.Value
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 3, &x, &lo, &hi); err != nil {
+		return nil, err
+	}
+	for _, v := range []starlark.Value{x, lo, hi} {
+		switch v.(type) {
+		case starlark.Int, starlark.Float:
+		default:
+			return nil, fmt.Errorf("%s: expected int or float, got %s", fn.Name(), v.Type())
+		}
+	}
+
+	if greater, err := starlark.Compare(syntax.GT, lo, hi); err != nil {
+		return nil, err
+	} else if greater {
+		return nil, fmt.Errorf("%s: lo %s is greater than hi %s", fn.Name(), lo, hi)
+	}
+	if less, err := starlark.Compare(syntax.LT, x, lo); err != nil {
+		return nil, err
+	} else if less {
+		return lo, nil
+	}
+	if greater, err := starlark.Compare(syntax.GT, x, hi); err != nil {
+		return nil, err
+	} else if greater {
+		return hi, nil
+	}
+	return x, nil
+}
+
+// starlarkCircuitBreaker returns circuit breaker with the given name, kept in the thread local like the random source,
+// so that it is the same breaker in every hook call, threshold and cooldown are updated on each call
+func starlarkCircuitBreaker(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	var threshold, cooldownMs int
+	if err := starlark.UnpackArgs(fn.Name(), args, kwargs, "name", &name, "threshold", &threshold, "cooldown_ms", &cooldownMs); err != nil {
+		return nil, err
+	}
+	if threshold <= 0 {
+		return nil, fmt.Errorf("%s: threshold must be positive, got %d", fn.Name(), threshold)
+	}
+	if cooldownMs < 0 {
+		return nil, fmt.Errorf("%s: cooldown_ms must not be negative, got %d", fn.Name(), cooldownMs)
+	}
+
+	breakers, ok := thread.Local(circuitBreakersLocalKey).(map[string]*circuitBreaker)
+	if !ok {
+		breakers = make(map[string]*circuitBreaker)
+		thread.SetLocal(circuitBreakersLocalKey, breakers)
+	}
+	cb, ok := breakers[name]
+	if !ok {
+		cb = &circuitBreaker{name: name}
+		breakers[name] = cb
+	}
+	cb.threshold = threshold
+	cb.cooldown = time.Duration(cooldownMs) * time.Millisecond
+
+	return cb, nil
+}
+
+//
+// Circuit breaker
+//
+
+// circuitBreakerState is the state of a circuit breaker
+type circuitBreakerState int
+
+const (
+	circuitClosed   circuitBreakerState = iota // Requests are allowed
+	circuitOpen                                // Requests are blocked until cooldown passes
+	circuitHalfOpen                            // Single trial request is allowed, its outcome closes or opens the breaker again
+)
+
+func (s circuitBreakerState) String() string {
+	switch s {
+	case circuitClosed:
+		return "closed"
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
+// circuitBreaker is a Starlark value, which opens after threshold consecutive failures and half-opens after cooldown,
+// used only by the thread which created it, so it needs no locking
+type circuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+
+	state    circuitBreakerState
+	failures int       // Consecutive failures while closed
+	openedAt time.Time // Time when breaker was last opened
+	trial    bool      // Trial request of half-open breaker is in flight
+}
+
+var _ starlark.HasAttrs = (*circuitBreaker)(nil)
+
+func (cb *circuitBreaker) String() string {
+	return fmt.Sprintf("circuit_breaker(%q, state=%s)", cb.name, cb.currentState())
+}
+func (cb *circuitBreaker) Type() string         { return "circuit_breaker" }
+func (cb *circuitBreaker) Freeze()              {} // State is changed only through methods, by the owning thread
+func (cb *circuitBreaker) Truth() starlark.Bool { return starlark.True }
+func (cb *circuitBreaker) Hash() (uint32, error) {
+	return 0, fmt.Errorf("unhashable type: %s", cb.Type())
+}
+
+func (cb *circuitBreaker) Attr(name string) (starlark.Value, error) {
+	switch name {
+	case "allow":
+		return starlark.NewBuiltin(name, cb.allow).BindReceiver(cb), nil
+	case "record_success":
+		return starlark.NewBuiltin(name, cb.recordSuccess).BindReceiver(cb), nil
+	case "record_failure":
+		return starlark.NewBuiltin(name, cb.recordFailure).BindReceiver(cb), nil
+	case "state":
+		return starlark.String(cb.currentState().String()), nil
+	}
+	return nil, nil
+}
+
+func (cb *circuitBreaker) AttrNames() []string {
+	return []string{"allow", "record_failure", "record_success", "state"}
+}
+
+// currentState returns breaker state, open breaker becomes half-open once cooldown has passed
+func (cb *circuitBreaker) currentState() circuitBreakerState {
+	if cb.state == circuitOpen && time.Since(cb.openedAt) >= cb.cooldown {
+		cb.state = circuitHalfOpen
+		cb.trial = false
+	}
+	return cb.state
+}
+
+// allow returns whether request may be sent, half-open breaker allows only a single trial request
+func (cb *circuitBreaker) allow(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	switch cb.currentState() {
+	case circuitClosed:
+		return starlark.True, nil
+	case circuitHalfOpen:
+		if !cb.trial {
+			cb.trial = true
+			return starlark.True, nil
+		}
+	}
+	return starlark.False, nil
+}
+
+// recordSuccess closes the breaker and resets consecutive failures
+func (cb *circuitBreaker) recordSuccess(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	cb.state = circuitClosed
+	cb.failures = 0
+	cb.trial = false
+	return starlark.None, nil
+}
+
+// recordFailure opens the breaker after threshold consecutive failures, or right away if trial request failed
+func (cb *circuitBreaker) recordFailure(thread *starlark.Thread, fn *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackPositionalArgs(fn.Name(), args, kwargs, 0); err != nil {
+		return nil, err
+	}
+	switch cb.currentState() {
+	case circuitClosed:
+		cb.failures++
+		if cb.failures >= cb.threshold {
+			cb.open()
+		}
+	case circuitHalfOpen:
+		cb.open()
+	}
+	return starlark.None, nil
+}
+
+// open opens the breaker, blocking requests until cooldown passes
+func (cb *circuitBreaker) open() {
+	cb.state = circuitOpen
+	cb.openedAt = time.Now()
+	cb.failures = 0
+	cb.trial = false
 }
 
 //
@@ -581,12 +1026,18 @@ func requestToDict(req *Request) *starlark.Dict {
 	if req == nil {
 		return starlark.NewDict(0)
 	}
-	d := starlark.NewDict(5)
+	d := starlark.NewDict(11)
 	d.SetKey(starlark.String("id"), starlark.String(req.Id))
 	d.SetKey(starlark.String("client_id"), starlark.String(req.ClientId))
 	d.SetKey(starlark.String("data"), starlark.String(req.Data))
 	d.SetKey(starlark.String("timestamp"), starlark.Float(float64(req.Timestamp.UnixNano())/1e6))
 	d.SetKey(starlark.String("meta"), req.Meta)
+	d.SetKey(starlark.String("idempotency_key"), starlark.String(req.IdempotencyKey))
+	d.SetKey(starlark.String("trace_id"), starlark.String(req.TraceId))
+	d.SetKey(starlark.String("kind"), starlark.String(req.Kind))
+	d.SetKey(starlark.String("is_retry"), starlark.Bool(req.IsRetry))
+	d.SetKey(starlark.String("new_connection"), starlark.Bool(req.NewConnection))
+	d.SetKey(starlark.String("key"), starlark.String(req.Key))
 	return d
 }
 
@@ -595,15 +1046,28 @@ func responseToDict(resp *Response) *starlark.Dict {
 	if resp == nil {
 		return starlark.NewDict(0)
 	}
-	d := starlark.NewDict(5)
+	d := starlark.NewDict(8)
 	d.SetKey(starlark.String("id"), starlark.String(resp.Id))
+	d.SetKey(starlark.String("trace_id"), starlark.String(resp.TraceId))
+	d.SetKey(starlark.String("outcome"), starlark.String(resp.Outcome.String()))
 	d.SetKey(starlark.String("ok"), starlark.Bool(resp.Ok))
 	d.SetKey(starlark.String("data"), starlark.String(resp.Data))
 	d.SetKey(starlark.String("error"), starlark.String(resp.Error))
 	d.SetKey(starlark.String("timestamp"), starlark.Float(float64(resp.Timestamp.UnixNano())/1e6))
+	d.SetKey(starlark.String("retry_after_ms"), starlark.MakeInt(resp.RetryAfterMs))
 	return d
 }
 
+// setCategory helper adds category of the failed attempt to request dict, so that hooks can branch on it
+// instead of parsing error message (empty if attempt succeeded)
+func setCategory(dict *starlark.Dict, resp *Response) {
+	category := OutcomeUnknown.Category()
+	if resp != nil {
+		category = resp.Outcome.Category()
+	}
+	dict.SetKey(starlark.String("category"), starlark.String(category))
+}
+
 // errorToValue helper converts error to Starlark string
 func errorToValue(err error) starlark.Value {
 	if err == nil {
@@ -612,13 +1076,34 @@ func errorToValue(err error) starlark.Value {
 	return starlark.String(err.Error())
 }
 
-// updateRequestFromDict helper updates Go Request from Starlark dict (metadata)
+// updateRequestFromDict helper updates Go Request from Starlark dict (metadata, idempotency key, trace id,
+// connection and resource key)
 func updateRequestFromDict(req *Request, dict *starlark.Dict) {
 	if value, found, _ := dict.Get(starlark.String("meta")); found {
 		if meta, ok := value.(*starlark.Dict); ok {
 			req.Meta = meta
 		}
 	}
+	if value, found, _ := dict.Get(starlark.String("idempotency_key")); found {
+		if key, ok := value.(starlark.String); ok {
+			req.IdempotencyKey = string(key)
+		}
+	}
+	if value, found, _ := dict.Get(starlark.String("trace_id")); found {
+		if traceId, ok := value.(starlark.String); ok {
+			req.TraceId = string(traceId)
+		}
+	}
+	if value, found, _ := dict.Get(starlark.String("new_connection")); found {
+		if newConnection, ok := value.(starlark.Bool); ok {
+			req.NewConnection = bool(newConnection)
+		}
+	}
+	if value, found, _ := dict.Get(starlark.String("key")); found {
+		if key, ok := value.(starlark.String); ok {
+			req.Key = string(key)
+		}
+	}
 }
 
 //
@@ -643,7 +1128,7 @@ func (b *NoopClientBehavior) OnError(req *Request, resp *Response) error {
 	return nil
 }
 
-func (b *NoopClientBehavior) OnFail(req *Request, rerr error) error {
+func (b *NoopClientBehavior) OnFail(req *Request, resp *Response, rerr error) error {
 	return nil
 }
 
@@ -651,4 +1136,8 @@ func (b *NoopClientBehavior) OnRetry(req *Request, resp *Response, rerr error) (
 	return false, 0, nil
 }
 
+func (b *NoopClientBehavior) HasRetryHook() bool {
+	return false
+}
+
 func (b *NoopClientBehavior) Close() {}
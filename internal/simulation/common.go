@@ -2,6 +2,8 @@ package simulation
 
 import (
 	"context"
+	"fmt"
+	"math/rand"
 	"time"
 
 	"go.starlark.net/starlark"
@@ -9,20 +11,111 @@ import (
 
 // Request data structure
 type Request struct {
-	Id        string
-	ClientId  string
-	Data      string
-	Timestamp time.Time
-	Meta      *starlark.Dict
+	Id             string
+	ClientId       string
+	Group          string // Id of the client group (tenant) the request is sent by
+	Data           string
+	Timestamp      time.Time
+	Meta           *starlark.Dict
+	IdempotencyKey string // Key for server-side deduplication of retries, settable from behavior script
+	TraceId        string // Id to correlate request with external traces, generated by client and settable from behavior script
+	BatchSize      int    // Number of logical operations batched into this request (0 or 1 is not batched)
+	SizeBytes      int    // Size of request body
+	Kind           string // Kind of request, like "read" or "write", sampled from client group's request kinds (empty if none)
+	IsRetry        bool   // Attempt is a retry of a failed attempt, server can shed retries first
+
+	CPUCost float64 // CPU weight relative to a default request, set from request kind (0 uses 1)
+
+	NewConnection bool // Request does not reuse client's open connection, settable from behavior script
+
+	Key string // Key of requested resource, concurrent requests with the same key are coalesced by single-flight server (empty is never coalesced)
+
+	received bool // Request was counted as received by server, so its rejection is counted too (set by network)
+	probe    bool // Debugging probe, kept out of run metrics and of client connection state
+}
+
+// payloadSize returns size of request body on the wire, size of request data if size is not set
+func (r Request) payloadSize() int {
+	if r.SizeBytes > 0 {
+		return r.SizeBytes
+	}
+	return len(r.Data)
+}
+
+// cpuWeight returns CPU weight of the request in server resource model
+func (r Request) cpuWeight() float64 {
+	if r.CPUCost <= 0 {
+		return 1
+	}
+	return r.CPUCost
+}
+
+// Outcome classifies how a request attempt ended
+type Outcome int
+
+const (
+	OutcomeUnknown     Outcome = iota // Not classified, e.g. simulation was stopped mid-request
+	OutcomeSuccess                    // Processed successfully
+	OutcomeServerError                // Processed by server, but failed
+	OutcomeRejected                   // Shed by server without processing (queue full, out of memory)
+	OutcomeTimeout                    // Client gave up waiting for response
+	OutcomeDropped                    // Request or response was lost in the network
+	OutcomeStale                      // Served successfully, but from stale data
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "success"
+	case OutcomeServerError:
+		return "server_error"
+	case OutcomeRejected:
+		return "rejected"
+	case OutcomeTimeout:
+		return "timeout"
+	case OutcomeDropped:
+		return "dropped"
+	case OutcomeStale:
+		return "stale"
+	default:
+		return "unknown"
+	}
+}
+
+// IsSuccess returns whether the outcome delivered a usable response to the client
+func (o Outcome) IsSuccess() bool {
+	return o == OutcomeSuccess || o == OutcomeStale
+}
+
+// Category classifies failed outcome for behavior scripts: network, timeout, server_error, rejected or dropped,
+// successful outcomes have no category
+func (o Outcome) Category() string {
+	switch o {
+	case OutcomeSuccess, OutcomeStale:
+		return ""
+	case OutcomeServerError, OutcomeRejected, OutcomeTimeout, OutcomeDropped:
+		return o.String()
+	default:
+		return "network" // No response for other reasons, e.g. connection was cancelled
+	}
 }
 
 // Response data structure
 type Response struct {
-	Id        string
-	Ok        bool
-	Data      string
-	Error     string
-	Timestamp time.Time
+	Id           string
+	TraceId      string // Trace id of the request this response answers
+	Outcome      Outcome
+	Ok           bool
+	Data         string
+	Error        string
+	Timestamp    time.Time
+	RetryAfterMs int // Suggested delay before retrying, set by server when it sheds load (0 if no hint)
+	SizeBytes    int // Size of response body on the wire, used for download time
+}
+
+// NewTraceId generates a random 128-bit trace id in hex, compatible with W3C trace context
+func NewTraceId(rng *rand.Rand) string {
+	return fmt.Sprintf("%016x%016x", rng.Uint64(), rng.Uint64())
 }
 
 // SleepWithContext sleeps for the specified duration, or returns an error if given context is cancelled
@@ -35,6 +128,26 @@ func SleepWithContext(ctx context.Context, duration time.Duration) error {
 	}
 }
 
+// CurveSample is a point of a curve function evaluated at X
+type CurveSample struct {
+	X float64 // Behavior time, ms
+	Y float64
+}
+
+// SampleCurve evaluates curve function at resolution points spread evenly over [0,maxX]
+func SampleCurve(fn func(x float64) float64, maxX float64, resolution int) []CurveSample {
+	if maxX <= 0 || resolution < 2 {
+		return []CurveSample{{X: 0, Y: fn(0)}}
+	}
+
+	samples := make([]CurveSample, resolution)
+	for i := range samples {
+		x := maxX * float64(i) / float64(resolution-1)
+		samples[i] = CurveSample{X: x, Y: fn(x)}
+	}
+	return samples
+}
+
 // CurveFunction returns a closure that computes y for a given x using the provided control points and bounds.
 // Implements the same interpolation logic as the frontend's mixedPath (Photoshop-like curves).
 func CurveFunction(minX, maxX, minY, maxY float64, points []BehaviorPoint) func(x float64) float64 {
@@ -122,6 +235,53 @@ func CurveFunction(minX, maxX, minY, maxY float64, points []BehaviorPoint) func(
 	}
 }
 
+// SpikeDimension defines which latency a scheduled spike adds to
+type SpikeDimension int
+
+const (
+	SpikeAll      SpikeDimension = iota // Server processing time, or both legs of the network trip
+	SpikeRequest                        // Request leg of the network trip only
+	SpikeResponse                       // Response leg of the network trip only
+)
+
+func (sd SpikeDimension) String() string {
+	switch sd {
+	case SpikeAll:
+		return "all"
+	case SpikeRequest:
+		return "request"
+	case SpikeResponse:
+		return "response"
+	default:
+		return "unknown"
+	}
+}
+
+// LatencySpike is extra latency added during a scheduled window of the behavior time axis
+type LatencySpike struct {
+	AtSec          float64
+	DurationSec    float64
+	ExtraLatencyMs float64
+	Dimension      SpikeDimension
+}
+
+// spikeExtraMs returns extra latency of the spikes of the dimension (or all dimensions) active at elapsed behavior time,
+// spike windows are stretched by time scale like behavior curves
+func spikeExtraMs(spikes []LatencySpike, dimension SpikeDimension, elapsedMs, timeScale float64) float64 {
+	var extraMs float64
+	for _, spike := range spikes {
+		if spike.Dimension != SpikeAll && spike.Dimension != dimension {
+			continue
+		}
+		startMs := spike.AtSec * 1000 * timeScale
+		endMs := (spike.AtSec + spike.DurationSec) * 1000 * timeScale
+		if elapsedMs >= startMs && elapsedMs < endMs {
+			extraMs += spike.ExtraLatencyMs
+		}
+	}
+	return extraMs
+}
+
 // sign returns the sign of a float64 (-1, 0, 1)
 func sign(x float64) int {
 	if x > 0 {
@@ -0,0 +1,97 @@
+package simulation
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// invariantCheckInterval is how often counters are checked against invariants during a run
+const invariantCheckInterval = 5 * time.Second
+
+// InvariantViolation describes a relationship between metrics counters which does not hold
+type InvariantViolation struct {
+	Invariant string           // Relationship expected to hold, like "server_success_resp + server_error_resp <= server_received_req"
+	Values    map[string]int64 // Values of the counters involved
+}
+
+// counterInvariant is an inequality between sums of counters, outcomes on the left never exceed requests on the right
+type counterInvariant struct {
+	name     string
+	outcomes []string
+	requests []string
+}
+
+// counterInvariants hold at any moment, requests still in flight only make the right side larger
+var counterInvariants = []counterInvariant{
+	{
+		name:     "client_success_resp + client_error_resp + client_timeout_req + network_failed_reqs <= client_sent_req",
+		outcomes: []string{"client_success_resp", "client_error_resp", "client_timeout_req", "network_failed_reqs"},
+		requests: []string{"client_sent_req"},
+	},
+	{
+		name:     "client_retry_req <= client_sent_req",
+		outcomes: []string{"client_retry_req"},
+		requests: []string{"client_sent_req"},
+	},
+	{
+		name:     "server_success_resp + server_error_resp <= server_received_req",
+		outcomes: []string{"server_success_resp", "server_error_resp"},
+		requests: []string{"server_received_req"},
+	},
+	{
+		name:     "server_success_resp + server_queue_rejected_req + server_dispatch_rejected_req + server_retry_shed_req <= server_received_req",
+		outcomes: []string{"server_success_resp", "server_queue_rejected_req", "server_dispatch_rejected_req", "server_retry_shed_req"},
+		requests: []string{"server_received_req"},
+	},
+}
+
+// CheckInvariants returns relationships between metrics counters which do not hold, empty if counting is consistent
+func (m *Metrics) CheckInvariants() []InvariantViolation {
+	// Counters of requests are loaded after counters of their outcomes, so that requests completed meanwhile
+	// are counted on both sides and do not show up as violations
+	outcomes := m.GetCounters()
+	requests := map[string]int64{
+		"client_sent_req":     m.ClientSentRequests.Load(),
+		"server_received_req": m.ServerReceivedRequests.Load(),
+	}
+
+	violations := make([]InvariantViolation, 0)
+	for _, invariant := range counterInvariants {
+		values := make(map[string]int64)
+		var left, right int64
+		for _, key := range invariant.outcomes {
+			values[key] = outcomes[key]
+			left += outcomes[key]
+		}
+		for _, key := range invariant.requests {
+			values[key] = requests[key]
+			right += requests[key]
+		}
+		if left > right {
+			violations = append(violations, InvariantViolation{Invariant: invariant.name, Values: values})
+		}
+	}
+	return violations
+}
+
+// runInvariantChecks periodically checks counters until the run context is cancelled, logging each violated invariant once
+func (m *Metrics) runInvariantChecks(ctx context.Context) {
+	ticker := time.NewTicker(invariantCheckInterval)
+	defer ticker.Stop()
+
+	logged := make(map[string]bool)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, violation := range m.CheckInvariants() {
+				if !logged[violation.Invariant] {
+					logged[violation.Invariant] = true
+					log.Printf("Metrics: Invariant violated: %s %v", violation.Invariant, violation.Values)
+				}
+			}
+		}
+	}
+}
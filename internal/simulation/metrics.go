@@ -17,13 +17,49 @@ type ResourceMetrics struct {
 	ThreadsUtilization float64
 	AverageQueueTimeMs float64
 	MaxQueueTimeMs     float64
+	ActiveIO           int64
+	IOUtilization      float64
+
+	DispatchQueuedRequests   int64
+	DispatchQueueUtilization float64
+
+	ResponseBytesInFlight int64
+}
+
+// PercentileMethod defines how percentiles are computed from a window of samples
+type PercentileMethod int
+
+const (
+	PercentileNearestRank  PercentileMethod = iota // Sample at the rank, jumps discretely with few samples
+	PercentileInterpolated                         // Linear interpolation between two closest ranks ("type 7")
+)
+
+func (pm PercentileMethod) String() string {
+	switch pm {
+	case PercentileNearestRank:
+		return "nearest-rank"
+	case PercentileInterpolated:
+		return "interpolated"
+	default:
+		return "unknown"
+	}
 }
 
 // Metrics tracks and computes statistics about the simulation
 type Metrics struct {
 	mu sync.RWMutex
 
-	ActiveClientsByGroup map[string]int64 // Current number of active clients per group
+	paused atomic.Bool // When set, counters and recorded values are frozen
+
+	ActiveClientsByGroup map[string]int64  // Current number of active clients per group
+	AttemptsHistogram    map[int]int64     // Completed requests (succeeded or given up) by number of attempts made
+	ResponsesByOutcome   map[Outcome]int64 // Request attempts seen by clients, by outcome
+
+	ResponsesByKind map[string]map[Outcome]int64 // Request attempts by request kind and outcome (only requests with kind)
+
+	// Client starvation metrics
+	clientLastSent      map[string]time.Time // Time of last sent request (or start) per active client
+	starvationThreshold time.Duration        // Time without sent requests after which active client is considered starved
 
 	// Client-side metrics
 	ClientBlockedRequests  atomic.Int64 // Requests blocked by clients' behavior
@@ -31,32 +67,89 @@ type Metrics struct {
 	ClientRetryRequests    atomic.Int64 // Requests retried by clients
 	ClientSuccessResponses atomic.Int64 // Successful responses received by clients
 	ClientErrorResponses   atomic.Int64 // Errorneous responses received by clients
+	ClientGoodResponses    atomic.Int64 // Successful responses received by clients before goodput deadline (not stale)
+	ClientTimeoutRequests  atomic.Int64 // Requests clients gave up waiting for, after request timeout
+	ClientAbandoned        atomic.Int64 // Clients which abandoned their session after consecutive failed requests
+
+	// Latency SLO metrics
+	sloLatency    time.Duration // Responses slower than this violate the SLO (0 disables tracking)
+	SLOResponses  atomic.Int64  // Responses checked against the SLO
+	SLOViolations atomic.Int64  // Responses slower than the SLO latency
 
 	// Network metrics
 	NetworkFailedRequests atomic.Int64 // Requests that failed to send/receive due to network errors
+	NetworkInFlight       atomic.Int64 // One-way trips currently in flight on the network, on both hops
+	NetworkPeakInFlight   atomic.Int64 // Highest number of one-way trips simultaneously in flight
+
+	NetworkBlackHoledRequests atomic.Int64 // Requests swallowed by the network, which never get any response
+	NetworkNewConnections     atomic.Int64 // Connections established by clients, each paying setup and handshake delay
+	NetworkReorderedResponses atomic.Int64 // Responses held in reorder buffer and overtaken by later ones
 
 	// Network latency metrics
-	MinRequestLatency  time.Duration   // Minimum latency on the way to the server (last 1s)
-	MaxRequestLatency  time.Duration   // Maximum latency on the way to the server (last 1s)
-	MinResponseLatency time.Duration   // Minimum latency on the way back from the server (last 1s)
-	MaxResponseLatency time.Duration   // Maximum latency on the way back from the server (last 1s)
+	MinRequestLatency  time.Duration   // Minimum latency on the way to the server (last window)
+	MaxRequestLatency  time.Duration   // Maximum latency on the way to the server (last window)
+	MinResponseLatency time.Duration   // Minimum latency on the way back from the server (last window)
+	MaxResponseLatency time.Duration   // Maximum latency on the way back from the server (last window)
 	RequestLatencies   []timedDuration // Array of recent request latencies with timestamps
 	ResponseLatencies  []timedDuration // Array of recent response latencies with timestamps
 
 	// Server-side metrics
-	ServerReceivedRequests atomic.Int64 // Requests received by server
-	ServerSuccessResponses atomic.Int64 // Successful responses returned by server
-	ServerErrorResponses   atomic.Int64 // Errorneous responses returned by server
+	ServerReceivedRequests     atomic.Int64 // Requests received by server
+	ServerSuccessResponses     atomic.Int64 // Successful responses returned by server
+	ServerErrorResponses       atomic.Int64 // Errorneous responses returned by server
+	ServerDeduplicatedRequests atomic.Int64 // Requests answered with the original response for a repeated idempotency key
+	ServerCoalescedRequests    atomic.Int64 // Requests which shared result of concurrent processing of the same key
+
+	// Server queue rejection metrics
+	ServerQueueRejectedRequests    atomic.Int64 // Requests rejected because accept queue was full
+	ServerDispatchRejectedRequests atomic.Int64 // Accepted requests rejected because worker dispatch queue was full
+	ServerRetryShedRequests        atomic.Int64 // Retries rejected early, because server deprioritizes retries under pressure
+
+	// Server fan-out metrics
+	ServerFanOutPartialRequests atomic.Int64 // Requests succeeded by quorum, with some of the backends failed
+	ServerFanOutFailedRequests  atomic.Int64 // Requests failed because fan-out quorum was not reached
 
 	// Response time metrics (sliding window)
 	trackDurationsCount int
+	percentileMethod    PercentileMethod
+	WindowDuration      time.Duration   // Sliding window of response time and network latency metrics
 	ResponseTimes       []timedDuration // Array of recent response times with timestamps
-	MinResponseTime     time.Duration   // Minimum response time (last 1s)
-	MaxResponseTime     time.Duration   // Maximum response time (last 1s)
-	AvgResponseTime     time.Duration   // Average response time (last 1s)
-	P50ResponseTime     time.Duration   // 50th percentile response time (last 1s)
-	P80ResponseTime     time.Duration   // 80th percentile response time (last 1s)
-	P95ResponseTime     time.Duration   // 95th percentile response time (last 1s)
+	MinResponseTime     time.Duration   // Minimum response time (last window)
+	MaxResponseTime     time.Duration   // Maximum response time (last window)
+	AvgResponseTime     time.Duration   // Average response time (last window)
+	P50ResponseTime     time.Duration   // 50th percentile response time (last window)
+	P80ResponseTime     time.Duration   // 80th percentile response time (last window)
+	P95ResponseTime     time.Duration   // 95th percentile response time (last window)
+	P99ResponseTime     time.Duration   // 99th percentile response time (last window)
+	P999ResponseTime    time.Duration   // 99.9th percentile response time (last window), the slowest one with few samples
+
+	apdexThreshold time.Duration // Responses up to this are satisfied and up to four times this are tolerating (0 disables Apdex)
+	apdexResponses int           // Responses the Apdex score was computed from (last window)
+	Apdex          float64       // Apdex score (last window), satisfied plus half of tolerating responses per response
+
+	// Goodput metrics (sliding window)
+	goodputDeadline  time.Duration // Successful responses slower than this are not useful (0 counts all of them)
+	GoodResponses    []time.Time   // Timestamps of recent useful responses received by clients
+	ServerSuccesses  []time.Time   // Timestamps of recent successful responses returned by server
+	GoodputRPS       int64         // Useful responses received by clients (last 1s)
+	ServerSuccessRPS int64         // Successful responses returned by server (last 1s)
+
+	// Throughput metrics (sliding window)
+	ClientSends       []time.Time // Timestamps of recent requests sent by clients
+	ServerReceives    []time.Time // Timestamps of recent requests received by server
+	ClientSuccesses   []time.Time // Timestamps of recent successful responses received by clients
+	ClientSentRPS     int64       // Requests sent by clients (last 1s)
+	ServerReceivedRPS int64       // Requests received by server (last 1s)
+	ClientSuccessRPS  int64       // Successful responses received by clients (last 1s)
+
+	// Warmup metrics
+	startTime              time.Time      // Time when the simulation was started
+	rampUpEnd              time.Time      // Time when all clients are expected to be started
+	targetClients          int64          // Configured number of clients of the run, all groups together
+	stabilizationErrorRate float64        // Windowed error rate below which the run is considered stabilized
+	firstSuccess           time.Duration  // Time from start to the first successful response (0 if none yet)
+	stabilized             time.Duration  // Time from start until the windowed error rate dropped below target (0 if not yet)
+	ResponseOutcomes       []timedOutcome // Array of recent response outcomes with timestamps
 
 	// Latest server resource state (pushed by Server)
 	latestResourceState ResourceMetrics
@@ -76,19 +169,212 @@ type timedDuration struct {
 	duration  time.Duration
 }
 
+// timedOutcome stores whether a response was successful and its timestamp
+type timedOutcome struct {
+	timestamp time.Time
+	ok        bool
+}
+
+// DefaultMetricsWindow is the default sliding window of response time and network latency metrics
+const DefaultMetricsWindow = time.Second
+
 // NewMetrics creates a new metrics tracker
 func NewMetrics() *Metrics {
 	return &Metrics{
 		ActiveClientsByGroup: make(map[string]int64),
+		AttemptsHistogram:    make(map[int]int64),
+		ResponsesByOutcome:   make(map[Outcome]int64),
+		ResponsesByKind:      make(map[string]map[Outcome]int64),
+		clientLastSent:       make(map[string]time.Time),
 		ResponseTimes:        make([]timedDuration, 0, 100000),
 		RequestLatencies:     make([]timedDuration, 0, 100000),
 		ResponseLatencies:    make([]timedDuration, 0, 100000),
+		ResponseOutcomes:     make([]timedOutcome, 0, 100000),
+		GoodResponses:        make([]time.Time, 0, 100000),
+		ServerSuccesses:      make([]time.Time, 0, 100000),
 		trackDurationsCount:  100000, // Track up to 100,000 recent durations for sliding window
+		WindowDuration:       DefaultMetricsWindow,
+	}
+}
+
+// discardMetrics records nothing as it is always paused, used for traffic kept out of run metrics
+var discardMetrics = func() *Metrics {
+	m := &Metrics{}
+	m.paused.Store(true)
+	return m
+}()
+
+// Start marks the beginning of a simulation run, rampUpEnd is the time when all clients are expected to be started,
+// targetClients is how many clients are configured
+func (m *Metrics) Start(startTime, rampUpEnd time.Time, targetClients int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.startTime = startTime
+	m.rampUpEnd = rampUpEnd
+	m.targetClients = targetClients
+	m.firstSuccess = 0
+	m.stabilized = 0
+}
+
+// rampUpProgress returns elapsed share of the ramp-up, 1 once all clients are expected to be started,
+// 0 before the run is started, must be called with mutex held
+func (m *Metrics) rampUpProgress(now time.Time) float64 {
+	if m.startTime.IsZero() {
+		return 0
+	}
+	rampUp := m.rampUpEnd.Sub(m.startTime)
+	if rampUp <= 0 {
+		return 1
+	}
+	return min(float64(now.Sub(m.startTime))/float64(rampUp), 1)
+}
+
+// SetRecording turns recording of metrics on or off, while off counters and sliding windows are not updated
+func (m *Metrics) SetRecording(recording bool) {
+	m.paused.Store(!recording)
+}
+
+// IsRecording returns whether metrics are being recorded
+func (m *Metrics) IsRecording() bool {
+	return !m.paused.Load()
+}
+
+// count increments the counter, if metrics are being recorded, and returns whether it was incremented
+func (m *Metrics) count(counter *atomic.Int64) bool {
+	if m.paused.Load() {
+		return false
 	}
+	counter.Add(1)
+	return true
 }
 
-// recordResponseTime updates the response time metrics using a sliding window of 1 second
+// SetPercentileMethod sets how response time percentiles are computed
+func (m *Metrics) SetPercentileMethod(method PercentileMethod) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.percentileMethod = method
+}
+
+// SetStabilizationErrorRate sets the windowed error rate target used to detect stabilization
+func (m *Metrics) SetStabilizationErrorRate(rate float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stabilizationErrorRate = rate
+}
+
+// SetGoodputDeadline sets response time after which successful responses are not counted in goodput
+func (m *Metrics) SetGoodputDeadline(deadline time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.goodputDeadline = deadline
+}
+
+// SetSLOLatency sets response time after which response violates the SLO, 0 disables tracking
+func (m *Metrics) SetSLOLatency(latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sloLatency = latency
+}
+
+// SetApdexThreshold sets response time up to which clients are satisfied, 0 disables the Apdex score
+func (m *Metrics) SetApdexThreshold(threshold time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apdexThreshold = threshold
+}
+
+// SetWindowDuration sets sliding window of response time and network latency metrics, default window if not positive
+func (m *Metrics) SetWindowDuration(window time.Duration) {
+	if window <= 0 {
+		window = DefaultMetricsWindow
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.WindowDuration = window
+}
+
+// SetStarvationThreshold sets time without sent requests after which active client is considered starved
+func (m *Metrics) SetStarvationThreshold(threshold time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.starvationThreshold = threshold
+}
+
+// startClientActivity starts tracking sent requests of the client
+func (m *Metrics) startClientActivity(clientId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clientLastSent[clientId] = time.Now()
+}
+
+// stopClientActivity stops tracking sent requests of the client
+func (m *Metrics) stopClientActivity(clientId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.clientLastSent, clientId)
+}
+
+// recordClientSent records the time of request sent by the client, and tracks sent requests using a sliding window of 1 second
+func (m *Metrics) recordClientSent(clientId string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	if _, active := m.clientLastSent[clientId]; active {
+		m.clientLastSent[clientId] = now
+	}
+	if !m.paused.Load() {
+		m.ClientSends = appendTimestamp(m.ClientSends, now, m.trackDurationsCount)
+	}
+}
+
+// recordClientSuccess counts successful response received by client, and tracks them using a sliding window of 1 second
+func (m *Metrics) recordClientSuccess() {
+	if m.paused.Load() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ClientSuccessResponses.Add(1)
+	m.ClientSuccesses = appendTimestamp(m.ClientSuccesses, time.Now(), m.trackDurationsCount)
+}
+
+// recordServerReceived counts request received by server, and tracks them using a sliding window of 1 second,
+// returns whether it was counted, response should not be counted if request was not
+func (m *Metrics) recordServerReceived() bool {
+	if m.paused.Load() {
+		return false
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ServerReceivedRequests.Add(1)
+	m.ServerReceives = appendTimestamp(m.ServerReceives, time.Now(), m.trackDurationsCount)
+	return true
+}
+
+// countStarvedClients returns number of active clients which have not sent a request for longer than threshold
+func (m *Metrics) countStarvedClients(now time.Time) int64 {
+	if m.starvationThreshold <= 0 {
+		return 0
+	}
+	var starved int64
+	for _, lastSent := range m.clientLastSent {
+		if now.Sub(lastSent) > m.starvationThreshold {
+			starved++
+		}
+	}
+	return starved
+}
+
+// recordResponseTime updates the response time metrics using a sliding window
 func (m *Metrics) recordResponseTime(responseTime time.Duration) {
+	if m.paused.Load() {
+		return
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -99,8 +385,113 @@ func (m *Metrics) recordResponseTime(responseTime time.Duration) {
 	}
 }
 
-// recordRequestLatency updates the request latency metrics using a sliding window of 1 second
+// recordResponseOutcome counts response outcomes (also by request kind, if request has one),
+// and tracks success using a sliding window of 1 second and the first successful response
+func (m *Metrics) recordResponseOutcome(outcome Outcome, kind string) {
+	if m.paused.Load() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ResponsesByOutcome[outcome]++
+	if kind != "" {
+		if m.ResponsesByKind[kind] == nil {
+			m.ResponsesByKind[kind] = make(map[Outcome]int64)
+		}
+		m.ResponsesByKind[kind][outcome]++
+	}
+
+	ok := outcome.IsSuccess()
+	now := time.Now()
+	if ok && m.firstSuccess == 0 && !m.startTime.IsZero() {
+		m.firstSuccess = now.Sub(m.startTime)
+	}
+
+	m.ResponseOutcomes = append(m.ResponseOutcomes, timedOutcome{timestamp: now, ok: ok})
+	if len(m.ResponseOutcomes) > m.trackDurationsCount {
+		m.ResponseOutcomes = m.ResponseOutcomes[len(m.ResponseOutcomes)-m.trackDurationsCount:]
+	}
+}
+
+// recordGoodput counts response as useful if it is successful, not stale and received before goodput deadline,
+// timed out responses are already discarded by clients
+func (m *Metrics) recordGoodput(outcome Outcome, responseTime time.Duration) {
+	if m.paused.Load() || outcome != OutcomeSuccess {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.goodputDeadline > 0 && responseTime > m.goodputDeadline {
+		return
+	}
+
+	m.ClientGoodResponses.Add(1)
+	m.GoodResponses = appendTimestamp(m.GoodResponses, time.Now(), m.trackDurationsCount)
+}
+
+// recordSLO checks response time of completed attempt against the SLO latency, if it is set
+func (m *Metrics) recordSLO(responseTime time.Duration) {
+	if m.paused.Load() {
+		return
+	}
+
+	m.mu.RLock()
+	sloLatency := m.sloLatency
+	m.mu.RUnlock()
+
+	if sloLatency <= 0 {
+		return
+	}
+
+	m.SLOResponses.Add(1)
+	if responseTime > sloLatency {
+		m.SLOViolations.Add(1)
+	}
+}
+
+// recordServerSuccess tracks successful server responses using a sliding window of 1 second, to compare with goodput
+func (m *Metrics) recordServerSuccess() {
+	if m.paused.Load() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ServerSuccessResponses.Add(1)
+	m.ServerSuccesses = appendTimestamp(m.ServerSuccesses, time.Now(), m.trackDurationsCount)
+}
+
+// appendTimestamp appends timestamp to the window, keeping at most limit recent ones
+func appendTimestamp(window []time.Time, timestamp time.Time, limit int) []time.Time {
+	window = append(window, timestamp)
+	if len(window) > limit {
+		window = window[len(window)-limit:]
+	}
+	return window
+}
+
+// recordAttempts counts a completed request (succeeded or given up) by the number of attempts it took
+func (m *Metrics) recordAttempts(attempts int) {
+	if m.paused.Load() {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.AttemptsHistogram[attempts]++
+}
+
+// recordRequestLatency updates the request latency metrics using a sliding window
 func (m *Metrics) recordRequestLatency(latency time.Duration) {
+	if m.paused.Load() {
+		return
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -111,8 +502,12 @@ func (m *Metrics) recordRequestLatency(latency time.Duration) {
 	}
 }
 
-// recordResponseLatency updates the response latency metrics using a sliding window of 1 second
+// recordResponseLatency updates the response latency metrics using a sliding window
 func (m *Metrics) recordResponseLatency(latency time.Duration) {
+	if m.paused.Load() {
+		return
+	}
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -127,16 +522,40 @@ func (m *Metrics) recordResponseLatency(latency time.Duration) {
 func (m *Metrics) GetSnapshot() map[string]any {
 	now := time.Now()
 
-	clientBlockedRequests := m.ClientBlockedRequests.Load()
-	clientSentRequests := m.ClientSentRequests.Load()
-	clientRetryRequests := m.ClientRetryRequests.Load()
+	// Outcomes are loaded before requests they are outcomes of, so that requests completed meanwhile
+	// do not make snapshot show more outcomes than requests
 	clientSuccessResponses := m.ClientSuccessResponses.Load()
 	clientErrorResponses := m.ClientErrorResponses.Load()
 	networkFailedRequests := m.NetworkFailedRequests.Load()
-	serverReceivedRequests := m.ServerReceivedRequests.Load()
+	clientTimeoutRequests := m.ClientTimeoutRequests.Load()
+	clientAbandoned := m.ClientAbandoned.Load()
 	serverSuccessResponses := m.ServerSuccessResponses.Load()
 	serverErrorResponses := m.ServerErrorResponses.Load()
 
+	clientBlockedRequests := m.ClientBlockedRequests.Load()
+	clientRetryRequests := m.ClientRetryRequests.Load()
+	clientSentRequests := m.ClientSentRequests.Load()
+	clientGoodResponses := m.ClientGoodResponses.Load()
+	sloViolations := m.SLOViolations.Load()
+	sloResponses := m.SLOResponses.Load()
+	sloViolationRate := 0.0
+	if sloResponses > 0 {
+		sloViolationRate = float64(sloViolations) / float64(sloResponses)
+	}
+	networkInFlight := m.NetworkInFlight.Load()
+	networkPeakInFlight := m.NetworkPeakInFlight.Load()
+	networkBlackHoledRequests := m.NetworkBlackHoledRequests.Load()
+	networkNewConnections := m.NetworkNewConnections.Load()
+	networkReorderedResponses := m.NetworkReorderedResponses.Load()
+	serverDeduplicatedRequests := m.ServerDeduplicatedRequests.Load()
+	serverCoalescedRequests := m.ServerCoalescedRequests.Load()
+	serverQueueRejectedRequests := m.ServerQueueRejectedRequests.Load()
+	serverDispatchRejectedRequests := m.ServerDispatchRejectedRequests.Load()
+	serverRetryShedRequests := m.ServerRetryShedRequests.Load()
+	serverFanOutPartialRequests := m.ServerFanOutPartialRequests.Load()
+	serverFanOutFailedRequests := m.ServerFanOutFailedRequests.Load()
+	serverReceivedRequests := m.ServerReceivedRequests.Load()
+
 	// Get latest ResourceState (thread-safe)
 	m.resourceStateMu.RLock()
 	state := m.latestResourceState
@@ -150,41 +569,128 @@ func (m *Metrics) GetSnapshot() map[string]any {
 	threadsUtilization := state.ThreadsUtilization
 	averageQueueTimeMs := state.AverageQueueTimeMs
 	maxQueueTimeMs := state.MaxQueueTimeMs
+	activeIO := state.ActiveIO
+	ioUtilization := state.IOUtilization
+	dispatchQueuedRequests := state.DispatchQueuedRequests
+	dispatchQueueUtilization := state.DispatchQueueUtilization
+	responseBytesInFlight := state.ResponseBytesInFlight
 
 	activeClientsByGroup := make(map[string]int64)
-	m.mu.RLock()
+	attemptsHistogram := make(map[int]int64)
+	responsesByOutcome := make(map[string]int64)
+	responsesByKind := make(map[string]map[string]int64)
+	// Write lock, as windows are trimmed and their metrics recalculated below,
+	// snapshot is taken concurrently by the metrics emitter, diffs and scrapes
+	m.mu.Lock()
 	maps.Copy(activeClientsByGroup, m.ActiveClientsByGroup)
+	var activeClientsTotal int64
+	for _, count := range m.ActiveClientsByGroup {
+		activeClientsTotal += count
+	}
+	targetClients := m.targetClients
+	rampUpPct := m.rampUpProgress(now) * 100
+	maps.Copy(attemptsHistogram, m.AttemptsHistogram)
+	for outcome, count := range m.ResponsesByOutcome {
+		responsesByOutcome[outcome.String()] = count
+	}
+	for kind, outcomes := range m.ResponsesByKind {
+		responsesByKind[kind] = make(map[string]int64)
+		for outcome, count := range outcomes {
+			responsesByKind[kind][outcome.String()] = count
+		}
+	}
 	minResponseTime := m.MinResponseTime.Milliseconds()
 	maxResponseTime := m.MaxResponseTime.Milliseconds()
 	avgResponseTime := m.AvgResponseTime.Milliseconds()
 	p50ResponseTime := m.P50ResponseTime.Milliseconds()
 	p80ResponseTime := m.P80ResponseTime.Milliseconds()
 	p95ResponseTime := m.P95ResponseTime.Milliseconds()
+	p99ResponseTime := m.P99ResponseTime.Milliseconds()
+	p999ResponseTime := m.P999ResponseTime.Milliseconds()
+	var apdex any
+	if m.apdexResponses > 0 {
+		apdex = m.Apdex
+	}
 	minRequestLatency := m.MinRequestLatency.Milliseconds()
 	maxRequestLatency := m.MaxRequestLatency.Milliseconds()
 	minResponseLatency := m.MinResponseLatency.Milliseconds()
 	maxResponseLatency := m.MaxResponseLatency.Milliseconds()
+	firstSuccessMs := durationMsOrNil(m.firstSuccess)
+	stabilizedMs := durationMsOrNil(m.stabilized)
+	clientStarved := m.countStarvedClients(now)
 	m.calculateSlidingWindowMetrics(now)
 	m.calculateNetworkLatencyMetrics(now)
-	m.mu.RUnlock()
+	m.calculateStabilization(now)
+	m.calculateGoodput(now)
+	m.calculateThroughput(now)
+	goodputRPS := m.GoodputRPS
+	serverSuccessRPS := m.ServerSuccessRPS
+	clientSentRPS := m.ClientSentRPS
+	serverReceivedRPS := m.ServerReceivedRPS
+	clientSuccessRPS := m.ClientSuccessRPS
+	m.mu.Unlock()
 
 	return map[string]any{
 		"active_clients": activeClientsByGroup,
 
+		// Live clients versus configured ones, live falling below target after ramp-up reveals attrition
+		"active_clients_total": activeClientsTotal,
+		"target_clients_total": targetClients,
+		"ramp_up_pct":          rampUpPct,
+
 		// Client-side metrics
-		"client_blocked_req":  clientBlockedRequests,
-		"client_sent_req":     clientSentRequests,
-		"client_retry_req":    clientRetryRequests,
-		"client_success_resp": clientSuccessResponses,
-		"client_error_resp":   clientErrorResponses,
+		"client_blocked_req":     clientBlockedRequests,
+		"client_sent_req":        clientSentRequests,
+		"client_retry_req":       clientRetryRequests,
+		"client_success_resp":    clientSuccessResponses,
+		"client_error_resp":      clientErrorResponses,
+		"client_good_resp":       clientGoodResponses,
+		"client_timeout_req":     clientTimeoutRequests,
+		"client_abandoned":       clientAbandoned,
+		"attempts_histogram":     attemptsHistogram,
+		"client_resp_by_outcome": responsesByOutcome,
+		"client_resp_by_kind":    responsesByKind,
+		"client_starved":         clientStarved,
+
+		// Latency SLO metrics, share of violations is the error budget burn
+		"slo_resp":           sloResponses,
+		"slo_violations":     sloViolations,
+		"slo_violation_rate": sloViolationRate,
+
+		// Goodput metrics (sliding window), useful responses versus raw server throughput
+		"goodput_rps":        goodputRPS,
+		"server_success_rps": serverSuccessRPS,
+
+		// Throughput metrics (sliding window)
+		"client_sent_rps":     clientSentRPS,
+		"server_received_rps": serverReceivedRPS,
+		"client_success_rps":  clientSuccessRPS,
 
 		// Network metrics
-		"network_failed_reqs": networkFailedRequests,
+		"network_failed_reqs":    networkFailedRequests,
+		"network_in_flight":      networkInFlight,
+		"network_peak_in_flight": networkPeakInFlight,
+
+		"network_black_holed_req": networkBlackHoledRequests,
+		"network_new_conn":        networkNewConnections,
+		"network_reordered_resp":  networkReorderedResponses,
 
 		// Server-side metrics
 		"server_received_req": serverReceivedRequests,
 		"server_success_resp": serverSuccessResponses,
 		"server_error_resp":   serverErrorResponses,
+		"server_dedup_req":    serverDeduplicatedRequests,
+
+		"server_coalesced_req": serverCoalescedRequests,
+
+		// Server queue rejection metrics
+		"server_queue_rejected_req":    serverQueueRejectedRequests,
+		"server_dispatch_rejected_req": serverDispatchRejectedRequests,
+		"server_retry_shed_req":        serverRetryShedRequests,
+
+		// Server fan-out metrics
+		"server_fanout_partial_req": serverFanOutPartialRequests,
+		"server_fanout_failed_req":  serverFanOutFailedRequests,
 
 		// ResourceState metrics (from server)
 		"server_cpu_utilization":     cpuUtilization,
@@ -195,26 +701,170 @@ func (m *Metrics) GetSnapshot() map[string]any {
 		"server_threads_utilization": threadsUtilization,
 		"server_avg_queue_time_ms":   averageQueueTimeMs,
 		"server_max_queue_time_ms":   maxQueueTimeMs,
+		"server_active_io":           activeIO,
+		"server_io_utilization":      ioUtilization,
+
+		"server_dispatch_queued_requests":   dispatchQueuedRequests,
+		"server_dispatch_queue_utilization": dispatchQueueUtilization,
+		"server_response_bytes_in_flight":   responseBytesInFlight,
 
 		// Response time metrics (sliding window)
-		"min_response_time": minResponseTime,
-		"max_response_time": maxResponseTime,
-		"avg_response_time": avgResponseTime,
-		"p50_response_time": p50ResponseTime,
-		"p80_response_time": p80ResponseTime,
-		"p95_response_time": p95ResponseTime,
+		"min_response_time_ms":  minResponseTime,
+		"max_response_time_ms":  maxResponseTime,
+		"avg_response_time_ms":  avgResponseTime,
+		"p50_response_time_ms":  p50ResponseTime,
+		"p80_response_time_ms":  p80ResponseTime,
+		"p95_response_time_ms":  p95ResponseTime,
+		"p99_response_time_ms":  p99ResponseTime,
+		"p999_response_time_ms": p999ResponseTime,
+
+		// Apdex score over the response time window, nil if disabled or no responses
+		"apdex": apdex,
 
 		// Network latency metrics
-		"min_request_latency":  minRequestLatency,
-		"max_request_latency":  maxRequestLatency,
-		"min_response_latency": minResponseLatency,
-		"max_response_latency": maxResponseLatency,
+		"min_request_latency_ms":  minRequestLatency,
+		"max_request_latency_ms":  maxRequestLatency,
+		"min_response_latency_ms": minResponseLatency,
+		"max_response_latency_ms": maxResponseLatency,
+
+		// Warmup metrics
+		"first_success_ms": firstSuccessMs,
+		"stabilized_ms":    stabilizedMs,
+
+		// Whether metrics are being recorded, or frozen
+		"recording": m.IsRecording(),
 
 		// Timestamp for client-side calculations
 		"timestamp": now.UnixMilli(),
+
+		// Units of the values above, keys which are not listed are counts
+		"units": snapshotUnits,
 	}
 }
 
+// snapshotUnits maps snapshot keys to units of their values, keys which are not listed are counts
+var snapshotUnits = map[string]string{
+	"ramp_up_pct": "percent",
+
+	"slo_violation_rate": "ratio",
+
+	"apdex": "ratio",
+
+	"goodput_rps":        "rps",
+	"server_success_rps": "rps",
+
+	"client_sent_rps":     "rps",
+	"server_received_rps": "rps",
+	"client_success_rps":  "rps",
+
+	"server_cpu_utilization":            "ratio",
+	"server_memory_utilization":         "ratio",
+	"server_queue_utilization":          "ratio",
+	"server_threads_utilization":        "ratio",
+	"server_io_utilization":             "ratio",
+	"server_dispatch_queue_utilization": "ratio",
+	"server_avg_queue_time_ms":          "ms",
+	"server_max_queue_time_ms":          "ms",
+	"server_response_bytes_in_flight":   "bytes",
+
+	"min_response_time_ms":    "ms",
+	"max_response_time_ms":    "ms",
+	"avg_response_time_ms":    "ms",
+	"p50_response_time_ms":    "ms",
+	"p80_response_time_ms":    "ms",
+	"p95_response_time_ms":    "ms",
+	"p99_response_time_ms":    "ms",
+	"p999_response_time_ms":   "ms",
+	"min_request_latency_ms":  "ms",
+	"max_request_latency_ms":  "ms",
+	"min_response_latency_ms": "ms",
+	"max_response_latency_ms": "ms",
+
+	"first_success_ms": "ms",
+	"stabilized_ms":    "ms",
+
+	"timestamp": "unix_ms",
+
+	"emitter_snapshot_ms": "ms", // Added by metrics emitter
+}
+
+// GetCounters returns current values of all counters, keyed the same way as in the snapshot,
+// counters of requests are loaded last, after counters of their outcomes (as in the snapshot)
+func (m *Metrics) GetCounters() map[string]int64 {
+	return map[string]int64{
+		"client_blocked_req":           m.ClientBlockedRequests.Load(),
+		"client_retry_req":             m.ClientRetryRequests.Load(),
+		"client_success_resp":          m.ClientSuccessResponses.Load(),
+		"client_error_resp":            m.ClientErrorResponses.Load(),
+		"client_good_resp":             m.ClientGoodResponses.Load(),
+		"client_timeout_req":           m.ClientTimeoutRequests.Load(),
+		"client_abandoned":             m.ClientAbandoned.Load(),
+		"slo_violations":               m.SLOViolations.Load(),
+		"slo_resp":                     m.SLOResponses.Load(), // After violations, which are counted after responses
+		"network_failed_reqs":          m.NetworkFailedRequests.Load(),
+		"network_black_holed_req":      m.NetworkBlackHoledRequests.Load(),
+		"network_new_conn":             m.NetworkNewConnections.Load(),
+		"network_reordered_resp":       m.NetworkReorderedResponses.Load(),
+		"server_success_resp":          m.ServerSuccessResponses.Load(),
+		"server_error_resp":            m.ServerErrorResponses.Load(),
+		"server_dedup_req":             m.ServerDeduplicatedRequests.Load(),
+		"server_coalesced_req":         m.ServerCoalescedRequests.Load(),
+		"server_queue_rejected_req":    m.ServerQueueRejectedRequests.Load(),
+		"server_dispatch_rejected_req": m.ServerDispatchRejectedRequests.Load(),
+		"server_retry_shed_req":        m.ServerRetryShedRequests.Load(),
+		"server_fanout_partial_req":    m.ServerFanOutPartialRequests.Load(),
+		"server_fanout_failed_req":     m.ServerFanOutFailedRequests.Load(),
+		"client_sent_req":              m.ClientSentRequests.Load(),
+		"server_received_req":          m.ServerReceivedRequests.Load(),
+	}
+}
+
+// responseTimePercentiles returns given percentiles (0..1) of response times recorded in the last 1s, without altering the window
+func (m *Metrics) responseTimePercentiles(now time.Time, percentiles ...float64) []time.Duration {
+	cutoff := now.Add(-1 * time.Second)
+
+	m.mu.RLock()
+	method := m.percentileMethod
+	times := make([]time.Duration, 0, len(m.ResponseTimes))
+	for _, tr := range m.ResponseTimes {
+		if !tr.timestamp.Before(cutoff) && !tr.timestamp.After(now) {
+			times = append(times, tr.duration)
+		}
+	}
+	m.mu.RUnlock()
+
+	result := make([]time.Duration, len(percentiles))
+	if len(times) == 0 {
+		return result
+	}
+
+	slices.Sort(times)
+	for i, p := range percentiles {
+		result[i] = percentile(times, p, method)
+	}
+
+	return result
+}
+
+// percentile returns p-th percentile (0..1) of sorted non-empty samples using the given method
+func percentile(sorted []time.Duration, p float64, method PercentileMethod) time.Duration {
+	if method == PercentileInterpolated {
+		rank := p * float64(len(sorted)-1)
+		lower := int(rank)
+		if lower >= len(sorted)-1 {
+			return sorted[len(sorted)-1]
+		}
+		fraction := rank - float64(lower)
+		return sorted[lower] + time.Duration(fraction*float64(sorted[lower+1]-sorted[lower]))
+	}
+
+	idx := int(float64(len(sorted)) * p)
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
+
 // AddActiveClient increments the active client count for a group
 func (m *Metrics) AddActiveClient(groupId string) {
 	m.mu.Lock()
@@ -229,9 +879,26 @@ func (m *Metrics) RemoveActiveClient(groupId string) {
 	m.ActiveClientsByGroup[groupId]--
 }
 
-// calculateSlidingWindowMetrics cleans up old values and calculates metrics for the current 1-second window
+// startTrip increments the number of one-way trips in flight, and updates the peak
+func (m *Metrics) startTrip() {
+	inFlight := m.NetworkInFlight.Add(1)
+	for {
+		peak := m.NetworkPeakInFlight.Load()
+		if inFlight <= peak || m.NetworkPeakInFlight.CompareAndSwap(peak, inFlight) {
+			return
+		}
+	}
+}
+
+// endTrip decrements the number of one-way trips in flight
+func (m *Metrics) endTrip() {
+	m.NetworkInFlight.Add(-1)
+}
+
+// calculateSlidingWindowMetrics cleans up old values and calculates metrics for the current window,
+// must be called with mutex locked for writing
 func (m *Metrics) calculateSlidingWindowMetrics(now time.Time) {
-	cutoff := now.Add(-1 * time.Second)
+	cutoff := now.Add(-m.WindowDuration)
 	filtered := m.ResponseTimes[:0]
 	for _, tr := range m.ResponseTimes {
 		if tr.timestamp.After(cutoff) || tr.timestamp.Equal(cutoff) {
@@ -264,23 +931,17 @@ func (m *Metrics) calculateSlidingWindowMetrics(now time.Time) {
 		// Sort for percentiles
 		slices.Sort(times)
 
-		p50Idx := int(float64(len(times)) * 0.5)
-		p80Idx := int(float64(len(times)) * 0.8)
-		p95Idx := int(float64(len(times)) * 0.95)
+		m.P50ResponseTime = percentile(times, 0.5, m.percentileMethod)
+		m.P80ResponseTime = percentile(times, 0.8, m.percentileMethod)
+		m.P95ResponseTime = percentile(times, 0.95, m.percentileMethod)
+		m.P99ResponseTime = percentile(times, 0.99, m.percentileMethod)
+		m.P999ResponseTime = percentile(times, 0.999, m.percentileMethod)
 
-		if p50Idx >= len(times) {
-			p50Idx = len(times) - 1
-		}
-		if p80Idx >= len(times) {
-			p80Idx = len(times) - 1
+		m.apdexResponses = 0
+		if m.apdexThreshold > 0 {
+			m.Apdex = apdexScore(times, m.apdexThreshold)
+			m.apdexResponses = len(times)
 		}
-		if p95Idx >= len(times) {
-			p95Idx = len(times) - 1
-		}
-
-		m.P50ResponseTime = times[p50Idx]
-		m.P80ResponseTime = times[p80Idx]
-		m.P95ResponseTime = times[p95Idx]
 	} else {
 		// No data in the last window, set metrics to zero
 		m.MinResponseTime = 0
@@ -289,12 +950,31 @@ func (m *Metrics) calculateSlidingWindowMetrics(now time.Time) {
 		m.P50ResponseTime = 0
 		m.P80ResponseTime = 0
 		m.P95ResponseTime = 0
+		m.P99ResponseTime = 0
+		m.P999ResponseTime = 0
+		m.apdexResponses = 0
+		m.Apdex = 0
+	}
+}
+
+// apdexScore returns share of satisfied (up to threshold) plus half of tolerating (up to four thresholds) response times
+func apdexScore(times []time.Duration, threshold time.Duration) float64 {
+	var satisfied, tolerating int
+	for _, rt := range times {
+		switch {
+		case rt <= threshold:
+			satisfied++
+		case rt <= 4*threshold:
+			tolerating++
+		}
 	}
+	return (float64(satisfied) + float64(tolerating)/2) / float64(len(times))
 }
 
-// calculateNetworkLatencyMetrics cleans up old values and calculates min/max for request/response latencies in the last 1s
+// calculateNetworkLatencyMetrics cleans up old values and calculates min/max for request/response latencies in the last window,
+// must be called with mutex locked for writing
 func (m *Metrics) calculateNetworkLatencyMetrics(now time.Time) {
-	cutoff := now.Add(-1 * time.Second)
+	cutoff := now.Add(-m.WindowDuration)
 
 	// Request latencies
 	filtered := m.RequestLatencies[:0]
@@ -354,3 +1034,72 @@ func (m *Metrics) calculateNetworkLatencyMetrics(now time.Time) {
 		m.MaxResponseLatency = 0
 	}
 }
+
+// calculateStabilization cleans up old outcomes and detects when the windowed error rate first drops below target after ramp-up
+func (m *Metrics) calculateStabilization(now time.Time) {
+	cutoff := now.Add(-1 * time.Second)
+	filtered := m.ResponseOutcomes[:0]
+	for _, to := range m.ResponseOutcomes {
+		if to.timestamp.After(cutoff) || to.timestamp.Equal(cutoff) {
+			filtered = append(filtered, to)
+		}
+	}
+	m.ResponseOutcomes = filtered
+
+	if m.stabilized != 0 || m.startTime.IsZero() || now.Before(m.rampUpEnd) || len(m.ResponseOutcomes) == 0 {
+		return
+	}
+
+	var failed int
+	for _, to := range m.ResponseOutcomes {
+		if !to.ok {
+			failed++
+		}
+	}
+
+	errorRate := float64(failed) / float64(len(m.ResponseOutcomes))
+	if errorRate < m.stabilizationErrorRate {
+		m.stabilized = now.Sub(m.startTime)
+	}
+}
+
+// calculateGoodput cleans up old timestamps and counts useful and successful server responses in the last 1s,
+// must be called with mutex locked for writing, as it trims the windows
+func (m *Metrics) calculateGoodput(now time.Time) {
+	cutoff := now.Add(-1 * time.Second)
+	m.GoodResponses = trimTimestamps(m.GoodResponses, cutoff)
+	m.ServerSuccesses = trimTimestamps(m.ServerSuccesses, cutoff)
+	m.GoodputRPS = int64(len(m.GoodResponses))
+	m.ServerSuccessRPS = int64(len(m.ServerSuccesses))
+}
+
+// calculateThroughput cleans up old timestamps and counts sent, received and successful requests in the last 1s,
+// must be called with mutex locked for writing, as it trims the windows
+func (m *Metrics) calculateThroughput(now time.Time) {
+	cutoff := now.Add(-1 * time.Second)
+	m.ClientSends = trimTimestamps(m.ClientSends, cutoff)
+	m.ServerReceives = trimTimestamps(m.ServerReceives, cutoff)
+	m.ClientSuccesses = trimTimestamps(m.ClientSuccesses, cutoff)
+	m.ClientSentRPS = int64(len(m.ClientSends))
+	m.ServerReceivedRPS = int64(len(m.ServerReceives))
+	m.ClientSuccessRPS = int64(len(m.ClientSuccesses))
+}
+
+// trimTimestamps removes timestamps before cutoff from the window
+func trimTimestamps(window []time.Time, cutoff time.Time) []time.Time {
+	filtered := window[:0]
+	for _, t := range window {
+		if !t.Before(cutoff) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// durationMsOrNil returns duration in milliseconds, or nil if duration is not set
+func durationMsOrNil(d time.Duration) any {
+	if d == 0 {
+		return nil
+	}
+	return d.Milliseconds()
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"slices"
 	"sync"
 	"time"
 )
@@ -17,6 +18,28 @@ type NetworkBehavior struct {
 	DropRate    []BehaviorPoint
 	LatencyMin  []BehaviorPoint
 	LatencyMax  []BehaviorPoint
+	// Bandwidth in bytes per second, adds transfer time proportional to request and response size on each hop (0 is unlimited)
+	BandwidthBytesPerSec int
+	// Probability that request silently vanishes and no response ever comes, only client timeout resolves it (0 disables)
+	BlackHoleRate float64
+	// Clamp sampled latency to [min, max] of the curves, otherwise max is the +3σ point of normal distribution
+	TruncateToRange bool
+	// One-time delays of establishing a new connection, paid by the first request of a client,
+	// after connection was idle for longer than idle timeout (0 keeps it open), or when behavior asks for a new one
+	ConnectionSetupMs       int
+	TLSHandshakeMs          int
+	ConnectionIdleTimeoutMs int
+	// Latency curves of the response leg, in the same range as request latency curves (empty uses request latency curves)
+	ResponseLatencyMin []BehaviorPoint
+	ResponseLatencyMax []BehaviorPoint
+	// Weight of previous latency in each new sample, in [0, 1), so that high latency clusters in time (0 disables)
+	LatencyCorrelation float64
+	// Probability that response of a request sent over a reused connection is held in reorder buffer until a later
+	// response over the same connection is delivered, for up to max delay (0 disables, requires connection setup delay)
+	ReorderRate       float64
+	ReorderMaxDelayMs int
+	// Extra latency added during scheduled windows of the behavior time axis, to request, response or both legs
+	LatencySpikes []LatencySpike
 }
 
 // Network simulates a network connection with configurable latency and packet loss
@@ -25,10 +48,29 @@ type Network struct {
 	metrics           *Metrics
 	behavior          NetworkBehavior
 	behaviorStartTime time.Time
+	timeScale         float64 // Multiplier for the behavior time axis
 	getDropRate       func(x float64) float64
 	getLatencyMin     func(x float64) float64
 	getLatencyMax     func(x float64) float64
 	mu                sync.RWMutex
+
+	getResponseLatencyMin func(x float64) float64 // Same as request latency functions, unless response leg has own curves
+	getResponseLatencyMax func(x float64) float64
+
+	connections map[string]time.Time // Time of last request over each client's open connection, by client id
+
+	lastRequestLatencyMs  float64 // Latency of the last request leg, blended into the next one when latency is correlated
+	lastResponseLatencyMs float64 // Latency of the last response leg, correlated separately, as response leg has own curves
+
+	reorderBuffers map[string][]*heldResponse // Responses held in reorder buffer of each client's connection, by client id
+	reorderSeq     uint64                     // Sequence number of the last response put into a reorder buffer
+}
+
+// heldResponse is a response held in reorder buffer until a later response over the same connection is delivered
+type heldResponse struct {
+	release   chan struct{} // Closed when a later response is delivered
+	seq       uint64        // Order in which responses were put into reorder buffers
+	overtaken bool          // Set (with mutex held) when released by a later response
 }
 
 // NewNetwork creates a new network simulator with the specified server
@@ -52,35 +94,86 @@ func NewNetwork(server *Server, metrics *Metrics) *Network {
 	}
 
 	n := &Network{
-		behavior: behavior,
-		server:   server,
-		metrics:  metrics,
+		behavior:  behavior,
+		timeScale: 1,
+		server:    server,
+		metrics:   metrics,
+
+		connections: make(map[string]time.Time),
+
+		reorderBuffers: make(map[string][]*heldResponse),
 	}
 
 	n.behaviorStartTime = time.Time{}
+	n.setupCurveFunctions()
+
+	return n
+}
+
+// setupCurveFunctions initializes curve functions from behavior, stretching time axis by time scale
+func (n *Network) setupCurveFunctions() {
+	behavior := n.behavior
+	maxX := float64(behavior.To) * 1000 * n.timeScale // maxX in ms
 	n.getDropRate = CurveFunction(
 		0,
-		float64(behavior.To)*1000,
+		maxX,
 		0,
 		1,
 		behavior.DropRate,
 	)
 	n.getLatencyMin = CurveFunction(
 		0,
-		float64(behavior.To)*1000,     // maxX in ms
+		maxX,
 		float64(behavior.LatencyFrom), // minY in ms
 		float64(behavior.LatencyTo),   // maxY in ms
 		behavior.LatencyMin,
 	)
 	n.getLatencyMax = CurveFunction(
 		0,
-		float64(behavior.To)*1000,     // maxX in ms
+		maxX,
 		float64(behavior.LatencyFrom), // minY in ms
 		float64(behavior.LatencyTo),   // maxY in ms
 		behavior.LatencyMax,
 	)
 
-	return n
+	n.getResponseLatencyMin = n.getLatencyMin
+	if len(behavior.ResponseLatencyMin) > 0 {
+		n.getResponseLatencyMin = CurveFunction(
+			0,
+			maxX,
+			float64(behavior.LatencyFrom), // minY in ms
+			float64(behavior.LatencyTo),   // maxY in ms
+			behavior.ResponseLatencyMin,
+		)
+	}
+	n.getResponseLatencyMax = n.getLatencyMax
+	if len(behavior.ResponseLatencyMax) > 0 {
+		n.getResponseLatencyMax = CurveFunction(
+			0,
+			maxX,
+			float64(behavior.LatencyFrom), // minY in ms
+			float64(behavior.LatencyTo),   // maxY in ms
+			behavior.ResponseLatencyMax,
+		)
+	}
+}
+
+// SampleCurves evaluates behavior curves over the behavior time range, keyed by curve name
+func (n *Network) SampleCurves(resolution int) map[string][]CurveSample {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	maxX := float64(n.behavior.To) * 1000 * n.timeScale
+	curves := map[string][]CurveSample{
+		"network_drops":   SampleCurve(n.getDropRate, maxX, resolution),
+		"network_lat_min": SampleCurve(n.getLatencyMin, maxX, resolution),
+		"network_lat_max": SampleCurve(n.getLatencyMax, maxX, resolution),
+	}
+	if len(n.behavior.ResponseLatencyMin) > 0 || len(n.behavior.ResponseLatencyMax) > 0 {
+		curves["network_resp_lat_min"] = SampleCurve(n.getResponseLatencyMin, maxX, resolution)
+		curves["network_resp_lat_max"] = SampleCurve(n.getResponseLatencyMax, maxX, resolution)
+	}
+	return curves
 }
 
 // GetBehavior returns the current network behavior
@@ -96,27 +189,18 @@ func (n *Network) SetBehavior(behavior NetworkBehavior) {
 	defer n.mu.Unlock()
 	n.behavior = behavior
 	n.behaviorStartTime = time.Time{}
-	n.getDropRate = CurveFunction(
-		0,
-		float64(behavior.To)*1000,
-		0,
-		1,
-		behavior.DropRate,
-	)
-	n.getLatencyMin = CurveFunction(
-		0,
-		float64(behavior.To)*1000,     // maxX in ms
-		float64(behavior.LatencyFrom), // minY in ms
-		float64(behavior.LatencyTo),   // maxY in ms
-		behavior.LatencyMin,
-	)
-	n.getLatencyMax = CurveFunction(
-		0,
-		float64(behavior.To)*1000,     // maxX in ms
-		float64(behavior.LatencyFrom), // minY in ms
-		float64(behavior.LatencyTo),   // maxY in ms
-		behavior.LatencyMax,
-	)
+	n.connections = make(map[string]time.Time)
+	n.lastRequestLatencyMs = 0
+	n.lastResponseLatencyMs = 0
+	n.setupCurveFunctions()
+}
+
+// SetTimeScale stretches (or shrinks) the behavior time axis by the given factor, without changing the behavior
+func (n *Network) SetTimeScale(timeScale float64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.timeScale = timeScale
+	n.setupCurveFunctions()
 }
 
 // ResetBehavior resets the network behavior to its initial state
@@ -124,8 +208,31 @@ func (n *Network) ResetBehavior() {
 	n.SetBehavior(n.GetBehavior())
 }
 
-// oneWayTrip simulates a one-way trip through the network using curves
-func (n *Network) oneWayTrip(ctx context.Context, elapsedMs float64, getDropRate, getLatencyMin, getLatencyMax func(x float64) float64) (time.Duration, error) {
+// transferTime returns time needed to transmit payload of the given size over the bandwidth-limited link
+func transferTime(sizeBytes, bandwidthBytesPerSec int) time.Duration {
+	if sizeBytes <= 0 || bandwidthBytesPerSec <= 0 {
+		return 0
+	}
+	return time.Duration(float64(sizeBytes) / float64(bandwidthBytesPerSec) * float64(time.Second))
+}
+
+// maxLatencyCorrelation is the highest weight of previous latency in a new sample, so that latency still follows
+// the curves instead of freezing at the first sample, or growing without bound above 1
+const maxLatencyCorrelation = 0.99
+
+// tripLeg is the direction of a one-way trip
+type tripLeg int
+
+const (
+	legRequest  tripLeg = iota // From client to server
+	legResponse                // From server back to client
+)
+
+// oneWayTrip simulates a one-way trip through the network using curves, transmission time is added to sampled latency
+func (n *Network) oneWayTrip(ctx context.Context, metrics *Metrics, leg tripLeg, elapsedMs float64, transmission time.Duration, truncateToRange bool, latencyCorrelation float64, getDropRate, getLatencyMin, getLatencyMax func(x float64) float64) (time.Duration, error) {
+	metrics.startTrip()
+	defer metrics.endTrip()
+
 	minLatency := getLatencyMin(elapsedMs)
 	maxLatency := getLatencyMax(elapsedMs)
 
@@ -143,10 +250,14 @@ func (n *Network) oneWayTrip(ctx context.Context, elapsedMs float64, getDropRate
 		mean := (min + max) / 2
 		stddev := (max - min) / 6
 		latencyMs = rand.NormFloat64()*stddev + mean
+		if truncateToRange {
+			latencyMs = math.Min(math.Max(latencyMs, min), max)
+		}
 	}
+	latencyMs = n.correlateLatency(leg, latencyCorrelation, latencyMs)
 
 	latencyMs = math.Max(latencyMs, 1) // not less than 1ms
-	latency := time.Duration(latencyMs) * time.Millisecond
+	latency := time.Duration(latencyMs)*time.Millisecond + transmission
 	err := SleepWithContext(ctx, latency)
 	if err != nil {
 		return latency, err // Context canceled, count as network error
@@ -161,8 +272,132 @@ func (n *Network) oneWayTrip(ctx context.Context, elapsedMs float64, getDropRate
 	return latency, nil
 }
 
+// correlateLatency blends sampled latency with latency of the previous trip in the same direction,
+// according to the given correlation, and remembers the result for the next trip
+func (n *Network) correlateLatency(leg tripLeg, correlation, latencyMs float64) float64 {
+	if correlation <= 0 {
+		return latencyMs
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	last := &n.lastRequestLatencyMs
+	if leg == legResponse {
+		last = &n.lastResponseLatencyMs
+	}
+	if *last > 0 {
+		latencyMs = correlation*(*last) + (1-correlation)*latencyMs
+	}
+	*last = latencyMs
+	return latencyMs
+}
+
+// connectionSetup returns delay of establishing a new connection for the request, or 0 if client's open connection
+// is reused, and marks the connection as used, must be called with mutex held
+func (n *Network) connectionSetup(req Request, now time.Time) time.Duration {
+	setupMs := n.behavior.ConnectionSetupMs + n.behavior.TLSHandshakeMs
+	if setupMs <= 0 {
+		return 0
+	}
+
+	// Probe has no connection of its own to reuse, and does not keep one open
+	if req.probe {
+		return time.Duration(setupMs) * time.Millisecond
+	}
+
+	lastUsed, open := n.connections[req.ClientId]
+	n.connections[req.ClientId] = now
+
+	idleTimeout := time.Duration(n.behavior.ConnectionIdleTimeoutMs) * time.Millisecond
+	if open && !req.NewConnection && (idleTimeout <= 0 || now.Sub(lastUsed) <= idleTimeout) {
+		return 0
+	}
+	return time.Duration(setupMs) * time.Millisecond
+}
+
+// reorderDelay returns how long at most response is held in reorder buffer, or 0 if it is delivered in order,
+// only responses over reused connections can be reordered, must be called with mutex held
+func (n *Network) reorderDelay(connectionSetup time.Duration) time.Duration {
+	reorderRate := n.behavior.ReorderRate
+	maxDelayMs := n.behavior.ReorderMaxDelayMs
+	reused := n.behavior.ConnectionSetupMs+n.behavior.TLSHandshakeMs > 0 && connectionSetup == 0
+	if !reused || reorderRate <= 0 || maxDelayMs <= 0 || rand.Float64() >= reorderRate {
+		return 0
+	}
+	return time.Duration(1+rand.Intn(maxDelayMs)) * time.Millisecond
+}
+
+// holdForReorder puts response into reorder buffer of the client's connection
+func (n *Network) holdForReorder(clientId string) *heldResponse {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.reorderSeq++
+	held := &heldResponse{release: make(chan struct{}), seq: n.reorderSeq}
+	n.reorderBuffers[clientId] = append(n.reorderBuffers[clientId], held)
+	return held
+}
+
+// deliverResponse releases responses held in reorder buffer of the client's connection before the delivered one,
+// as the delivered response overtakes them, and returns whether the delivered response (nil if it was not held,
+// which overtakes all held responses) was overtaken itself. Responses held after it stay in the buffer
+func (n *Network) deliverResponse(clientId string, delivered *heldResponse) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	buffer := slices.DeleteFunc(n.reorderBuffers[clientId], func(held *heldResponse) bool {
+		if held == delivered || (delivered != nil && held.seq > delivered.seq) {
+			return held == delivered
+		}
+		held.overtaken = true
+		close(held.release)
+		return true
+	})
+	if len(buffer) == 0 {
+		delete(n.reorderBuffers, clientId)
+	} else {
+		n.reorderBuffers[clientId] = buffer
+	}
+	return delivered != nil && delivered.overtaken
+}
+
+// discardHeld removes response, which is not going to be delivered, from reorder buffer of the client's connection
+func (n *Network) discardHeld(clientId string, discarded *heldResponse) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	buffer := slices.DeleteFunc(n.reorderBuffers[clientId], func(held *heldResponse) bool { return held == discarded })
+	if len(buffer) == 0 {
+		delete(n.reorderBuffers, clientId)
+	} else {
+		n.reorderBuffers[clientId] = buffer
+	}
+}
+
+// SendTrace holds the timing breakdown of a single request passing through the network
+type SendTrace struct {
+	ConnectionSetup time.Duration // Zero if connection was reused
+	RequestLatency  time.Duration
+	ProcessingTime  time.Duration
+	ResponseLatency time.Duration
+	ReorderDelay    time.Duration // Time response was held in reorder buffer, zero if it was delivered in order
+}
+
 // Send transmits a request through the simulated network to the server
 func (n *Network) Send(ctx context.Context, req Request) (Response, error) {
+	return n.send(ctx, req, nil)
+}
+
+// SendTraced transmits a request like Send, but also returns the timing breakdown of each hop
+func (n *Network) SendTraced(ctx context.Context, req Request) (Response, SendTrace, error) {
+	var trace SendTrace
+	resp, err := n.send(ctx, req, &trace)
+	return resp, trace, err
+}
+
+// send transmits a request through the network, filling the trace if it is not nil
+func (n *Network) send(ctx context.Context, req Request, trace *SendTrace) (Response, error) {
 	n.mu.Lock()
 	if n.behaviorStartTime.IsZero() {
 		n.behaviorStartTime = time.Now()
@@ -171,32 +406,118 @@ func (n *Network) Send(ctx context.Context, req Request) (Response, error) {
 	getDropRate := n.getDropRate
 	getLatencyMin := n.getLatencyMin
 	getLatencyMax := n.getLatencyMax
+	getResponseLatencyMin := n.getResponseLatencyMin
+	getResponseLatencyMax := n.getResponseLatencyMax
+	bandwidth := n.behavior.BandwidthBytesPerSec
+	blackHoleRate := n.behavior.BlackHoleRate
+	truncateToRange := n.behavior.TruncateToRange
+	latencyCorrelation := min(max(n.behavior.LatencyCorrelation, 0), maxLatencyCorrelation)
+	latencySpikes := n.behavior.LatencySpikes
+	timeScale := n.timeScale
+	connectionSetup := n.connectionSetup(req, time.Now())
+	reorderDelay := n.reorderDelay(connectionSetup)
+	reordering := n.behavior.ReorderRate > 0 && !req.probe
 	n.mu.Unlock()
 
+	// Probe traffic is kept out of run metrics
+	metrics := n.metrics
+	if req.probe {
+		metrics = discardMetrics
+	}
+
+	// Black hole, unlike dropped packet there is no error either, request hangs until caller gives up
+	if blackHoleRate > 0 && rand.Float64() < blackHoleRate {
+		metrics.count(&metrics.NetworkBlackHoledRequests)
+		<-ctx.Done()
+		return Response{Outcome: OutcomeDropped}, ctx.Err()
+	}
+
+	// New connection has to be established before the request goes
+	if connectionSetup > 0 {
+		metrics.count(&metrics.NetworkNewConnections)
+		if trace != nil {
+			trace.ConnectionSetup = connectionSetup
+		}
+		err := SleepWithContext(ctx, connectionSetup)
+		if err != nil {
+			return Response{Outcome: OutcomeDropped}, err
+		}
+	}
+
 	elapsedMs := float64(time.Since(behaviorStart).Milliseconds())
-	requestLatency, requestLostErr := n.oneWayTrip(ctx, elapsedMs, getDropRate, getLatencyMin, getLatencyMax)
-	n.metrics.recordRequestLatency(requestLatency)
+	upload := transferTime(req.payloadSize(), bandwidth)
+	upload += time.Duration(spikeExtraMs(latencySpikes, SpikeRequest, elapsedMs, timeScale) * float64(time.Millisecond)) // Added to latency like transfer time
+	requestLatency, requestLostErr := n.oneWayTrip(ctx, metrics, legRequest, elapsedMs, upload, truncateToRange, latencyCorrelation, getDropRate, getLatencyMin, getLatencyMax)
+	metrics.recordRequestLatency(requestLatency)
+	if trace != nil {
+		trace.RequestLatency = requestLatency
+	}
 	if requestLostErr != nil {
-		return Response{}, requestLostErr
+		return Response{Outcome: OutcomeDropped}, requestLostErr
 	}
 
-	n.metrics.ServerReceivedRequests.Add(1)
+	received := metrics.recordServerReceived() // Response is counted only if request was
+	req.received = received
+	processingStart := time.Now()
 	resp, err := n.server.HandleRequest(ctx, req)
+	if trace != nil {
+		trace.ProcessingTime = time.Since(processingStart)
+	}
 	if err == nil && resp.Ok {
-		n.metrics.ServerSuccessResponses.Add(1)
+		if received {
+			metrics.recordServerSuccess()
+		}
 	} else {
-		n.metrics.ServerErrorResponses.Add(1)
+		if received {
+			metrics.count(&metrics.ServerErrorResponses)
+		}
 		resp.Ok = false
+		if resp.Outcome == OutcomeUnknown || resp.Outcome.IsSuccess() {
+			resp.Outcome = OutcomeServerError
+		}
+		if resp.TraceId == "" {
+			resp.TraceId = req.TraceId
+		}
 		if resp.Error == "" && err != nil {
 			resp.Error = err.Error()
 		}
 	}
 
+	// Server keeps response buffered while it is on the way back
 	elapsedMs = float64(time.Since(behaviorStart).Milliseconds())
-	responseLatency, responseLostErr := n.oneWayTrip(ctx, elapsedMs, getDropRate, getLatencyMin, getLatencyMax)
-	n.metrics.recordResponseLatency(responseLatency)
+	download := transferTime(resp.SizeBytes, bandwidth)
+	download += time.Duration(spikeExtraMs(latencySpikes, SpikeResponse, elapsedMs, timeScale) * float64(time.Millisecond))
+	releaseResponse := n.server.holdResponse(resp.SizeBytes)
+	responseLatency, responseLostErr := n.oneWayTrip(ctx, metrics, legResponse, elapsedMs, download, truncateToRange, latencyCorrelation, getDropRate, getResponseLatencyMin, getResponseLatencyMax)
+	releaseResponse()
+	metrics.recordResponseLatency(responseLatency)
+	if trace != nil {
+		trace.ResponseLatency = responseLatency
+	}
 	if responseLostErr != nil {
-		return Response{}, responseLostErr
+		return Response{Outcome: OutcomeDropped}, responseLostErr
+	}
+
+	// Response is held in reorder buffer until a response of a later request over the same connection is delivered,
+	// or until max delay passes, any delivered response releases responses held before it
+	if reorderDelay > 0 {
+		held := n.holdForReorder(req.ClientId)
+		heldAt := time.Now()
+		select {
+		case <-held.release:
+		case <-time.After(reorderDelay):
+		case <-ctx.Done():
+			n.discardHeld(req.ClientId, held)
+			return Response{Outcome: OutcomeDropped}, ctx.Err()
+		}
+		if trace != nil {
+			trace.ReorderDelay = time.Since(heldAt)
+		}
+		if n.deliverResponse(req.ClientId, held) {
+			metrics.count(&metrics.NetworkReorderedResponses)
+		}
+	} else if reordering {
+		n.deliverResponse(req.ClientId, nil)
 	}
 
 	return resp, nil
@@ -0,0 +1,73 @@
+package simulation
+
+import (
+	"sync"
+)
+
+// ScriptPool is a bounded set of goroutines which execute hooks of many client behavior scripts,
+// instead of one executor goroutine per behavior.
+// Each behavior keeps its own queue of hook calls and Starlark thread (with script state and random source),
+// and is executed by at most one worker at a time, so hooks of one client are still run sequentially and in order.
+type ScriptPool struct {
+	ready chan *StarlarkClientBehavior // Behaviors with pending hook calls, waiting for a worker
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewScriptPool creates a pool and starts the given number of workers
+func NewScriptPool(workers int) *ScriptPool {
+	p := &ScriptPool{
+		ready: make(chan *StarlarkClientBehavior, workers),
+		stop:  make(chan struct{}),
+	}
+	for range workers {
+		p.wg.Go(p.worker)
+	}
+	return p
+}
+
+// Stop stops workers and waits for them to finish current hook calls
+func (p *ScriptPool) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// schedule queues behavior for execution, unless it is already queued or being executed
+func (p *ScriptPool) schedule(b *StarlarkClientBehavior) {
+	if !b.scheduled.CompareAndSwap(false, true) {
+		return
+	}
+
+	select {
+	case p.ready <- b:
+	case <-b.stopChan:
+	case <-p.stop:
+	}
+}
+
+// worker executes pending hook calls of scheduled behaviors
+func (p *ScriptPool) worker() {
+	for {
+		select {
+		case b := <-p.ready:
+			p.drain(b)
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// drain executes all pending hook calls of the behavior, then releases it (closed behavior is dropped).
+// Workers never send to the ready channel themselves, so if new calls arrive right after the queue
+// is drained, the worker picks the behavior up again instead of scheduling it.
+func (p *ScriptPool) drain(b *StarlarkClientBehavior) {
+	for {
+		if !b.executePending() {
+			return
+		}
+		b.scheduled.Store(false)
+		if len(b.executionChan) == 0 || !b.scheduled.CompareAndSwap(false, true) {
+			return
+		}
+	}
+}
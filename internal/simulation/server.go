@@ -3,13 +3,29 @@ package simulation
 import (
 	"context"
 	"fmt"
+	"log"
 	"math"
 	"math/rand"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 )
 
+// minRetryAfterMs is the lowest retry delay hint the server suggests for shed requests
+const minRetryAfterMs = 100
+
+// retryShedQueueUtilization is accept queue utilization from which retries are rejected, when server deprioritizes them,
+// so that the rest of the queue is left for first attempts
+const retryShedQueueUtilization = 0.5
+
+// minCapacityWarmupFraction is the lowest share of CapacityRPS available during warmup when server has no worker pool
+const minCapacityWarmupFraction = 0.1
+
+// sharedLatencyNoiseTime is how long the shared latency component takes to decorrelate, so spikes last about this long
+const sharedLatencyNoiseTime = time.Second
+
 // ResourceSettings represents resource configuration (part of behavior)
 type ResourceSettings struct {
 	MaxConcurrentRequests  int
@@ -17,8 +33,28 @@ type ResourceSettings struct {
 	MaxQueueSize           int
 	MemoryLeakRateMBPerSec float64
 	MemoryPerRequestMB     float64
+	MemoryPerResponseKB    float64 // Memory (KB) held per KB of response body buffered until delivered (0 disables)
 	GCPauseIntervalSec     float64
 	GCPauseDurationMs      float64
+	// Processing time multiplier increase per request queued ahead at enqueue time (0 disables)
+	QueueDepthLatencyFactor float64
+	// Fraction of requests that are IO-bound, and size of the IO pool they contend for (0 disables)
+	IOBoundFraction float64
+	MaxConcurrentIO int
+	// Size of worker dispatch (thread-pool) queue between accept queue and workers, requests accepted
+	// into the accept queue are rejected when it is full (0 disables, workers take from accept queue)
+	MaxDispatchQueueSize int
+	// Memory utilization above which server swaps, and response time multiplier at the threshold,
+	// which grows up to twice as large as memory climbs to max (0 disables)
+	SwapThreshold         float64
+	SwapLatencyMultiplier float64
+	// Weights of CPU, memory and thread contention impacts on response time (all 0 keeps equal weights of 1),
+	// summed instead of compounded when additive, and cap of the resulting multiplier (0 disables)
+	CPUImpactWeight             float64
+	MemoryImpactWeight          float64
+	ThreadsImpactWeight         float64
+	AdditiveResourceImpact      bool
+	MaxResourceImpactMultiplier float64
 }
 
 // ResourceState represents current server resource state (runtime values)
@@ -31,13 +67,25 @@ type ResourceState struct {
 	ThreadsUtilization float64
 	AverageQueueTimeMs float64
 	MaxQueueTimeMs     float64
+	IORequests         int64 // IO-bound requests in progress, waiting for or holding an IO slot
+	ActiveIO           int64 // IO-bound requests holding an IO slot
+	IOUtilization      float64
+
+	DispatchQueued           int64 // Requests waiting in the dispatch queue
+	DispatchQueueUtilization float64
+
+	ResponseBytesInFlight int64 // Bytes of responses buffered until delivered to clients
+
+	ActiveCPUCost float64 // Sum of CPU weights of active requests, CPU utilization is derived from it
+	IOCPUCost     float64 // Sum of CPU weights of IO-bound requests in progress, which do not burn CPU
 }
 
 // QueuedRequest represents a request waiting in queue
 type QueuedRequest struct {
-	Request  Request
-	QueuedAt time.Time
-	Response chan QueuedResponse
+	Request    Request
+	QueuedAt   time.Time
+	QueueDepth int // Number of requests queued ahead at enqueue time
+	Response   chan QueuedResponse
 }
 
 // QueuedResponse represents the response from processing a queued request
@@ -56,6 +104,61 @@ type ServerBehavior struct {
 	ResponseTimeMax          []BehaviorPoint
 	EnableResourceManagement bool
 	ResourceSettings         ResourceSettings
+	// Sustainable throughput in requests per second, when set work time is M/M/1-style time in queue including service
+	// at this rate instead of response time curves (0 uses curves)
+	CapacityRPS float64
+	// Time window to return the original response for requests with repeated idempotency key (0 disables)
+	IdempotencyWindowMs int
+	// Time for capacity (worker count and CapacityRPS) to ramp up to full after start, models pools and caches filling (0 disables)
+	CapacityWarmupSec float64
+	// Fraction of capacity available right after start, grows linearly to full over warmup (at least one worker's share)
+	CapacityWarmupFraction float64
+	// Size of successful response body in bytes, transferred over the network on the way back (0 uses actual data size)
+	ResponseSizeBytes int
+	// Per client group (tenant) adjustments of behavior, keyed by group id
+	GroupOverrides map[string]GroupOverride
+	// Number of backends each request is scattered to, and probability that a single backend call fails (0 disables)
+	FanOutBackends         int
+	FanOutBackendErrorRate float64
+	// Number of backends which have to succeed for request to succeed (0 requires all)
+	FanOutQuorum int
+	// Share of response time variance common to all concurrent requests, in [0,1], models contention on shared resources (0 disables)
+	LatencyCorrelation float64
+	// Fraction of each request's work done in a server-wide critical section, one request at a time (Amdahl's law, 0 disables)
+	SerialFraction float64
+	// Starlark script with `on_handle(req)` hook, which can override or add to work time and fail requests (empty uses curves only)
+	Script string
+	// Shed retries first under pressure, rejecting them once accept queue is half full (requires resource management)
+	DeprioritizeRetries bool
+	// Clamp sampled response time to [min, max] of the curves, otherwise max is the +3σ point of normal distribution
+	TruncateToRange bool
+	// Concurrent requests with the same key share one processing and its result, requests without key are processed separately
+	SingleFlight bool
+	// Extra processing time added during scheduled windows of the behavior time axis (dimension is ignored)
+	LatencySpikes []LatencySpike
+	// Body of successful responses, with {request_id}, {trace_id}, {client_id}, {group}, {kind} and {elapsed_ms}
+	// (behavior time) substituted, templates by request kind take precedence (empty responds "OK")
+	ResponseTemplate        string
+	ResponseTemplatesByKind map[string]string
+}
+
+// GroupOverride adjusts server behavior for requests from a specific client group
+type GroupOverride struct {
+	ResponseTimeMultiplier float64 // Multiplier for processing time
+	ErrorRateMultiplier    float64 // Multiplier for error rate
+}
+
+// idempotencyEntry stores the original response for an idempotency key
+type idempotencyEntry struct {
+	response  Response
+	expiresAt time.Time
+}
+
+// singleFlightCall is processing of a request, whose result is shared by concurrent requests with the same key
+type singleFlightCall struct {
+	done chan struct{} // Closed when processing is done and result is set
+	resp Response
+	err  error
 }
 
 // Server represents the server with both configuration and runtime state
@@ -64,9 +167,11 @@ type Server struct {
 	metrics            *Metrics
 	behavior           ServerBehavior
 	behaviorStartTime  time.Time
+	timeScale          float64 // Multiplier for the behavior time axis
 	getErrorRate       func(x float64) float64
 	getResponseTimeMin func(x float64) float64
 	getResponseTimeMax func(x float64) float64
+	transitionCancel   context.CancelFunc // Cancels ongoing behavior transition
 
 	resourceSettings ResourceSettings
 	resourceState    ResourceState
@@ -75,9 +180,33 @@ type Server struct {
 	startTime        time.Time
 
 	requestQueue chan QueuedRequest
+	ioSemaphore  chan struct{}
 	queueTimes   []float64
 	queueTimesMu sync.Mutex
 
+	dispatchQueue chan QueuedRequest // Worker dispatch queue after accept queue, nil when disabled
+
+	responseBytesInFlight atomic.Int64 // Bytes of responses buffered until delivered to clients
+
+	capacityBusyUntil time.Time // Time when the virtual capacity queue drains
+	capacityMu        sync.Mutex
+
+	idempotencyCache     map[string]idempotencyEntry
+	idempotencyLastSweep time.Time
+	idempotencyMu        sync.Mutex
+
+	singleFlightCalls map[string]*singleFlightCall // Requests being processed by single-flight server, by key
+	singleFlightMu    sync.Mutex
+
+	criticalSection chan struct{} // Server-wide lock for the serial fraction of work, cancellable unlike mutex
+
+	sharedLatencyNoise   float64   // Slowly varying standard normal noise, common to all requests
+	sharedLatencyNoiseAt time.Time // Time when shared latency noise was last updated
+
+	script *StarlarkServerBehavior // Compiled behavior script, only while server is running (nil if not set)
+
+	behaviorsDir string // Base directory for behavior script file references (empty disables them)
+
 	ctx     context.Context
 	cancel  context.CancelFunc
 	running atomic.Bool
@@ -120,9 +249,14 @@ func NewServer(id string, metrics *Metrics) *Server {
 		id:               id,
 		metrics:          metrics,
 		behavior:         behavior,
+		timeScale:        1,
 		resourceSettings: behavior.ResourceSettings,
 		resourceState:    ResourceState{},
 		queueTimes:       make([]float64, 0, 100),
+		idempotencyCache: make(map[string]idempotencyEntry),
+		criticalSection:  make(chan struct{}, 1),
+
+		singleFlightCalls: make(map[string]*singleFlightCall),
 	}
 
 	s.setupCurveFunctions()
@@ -140,45 +274,97 @@ func (s *Server) Start(simulationCtx context.Context) error {
 	defer s.mu.Unlock()
 
 	s.ctx, s.cancel = context.WithCancel(simulationCtx)
+	s.startTime = time.Now()
+	s.restartScript()
 
 	if s.behavior.EnableResourceManagement {
 		s.resourceStateMu.Lock()
 		s.resourceState = ResourceState{}
 		s.requestQueue = make(chan QueuedRequest, s.resourceSettings.MaxQueueSize)
+		s.dispatchQueue = nil
+		if s.resourceSettings.MaxDispatchQueueSize > 0 {
+			s.dispatchQueue = make(chan QueuedRequest, s.resourceSettings.MaxDispatchQueueSize)
+		}
+		s.ioSemaphore = nil
+		if s.resourceSettings.MaxConcurrentIO > 0 {
+			s.ioSemaphore = make(chan struct{}, s.resourceSettings.MaxConcurrentIO)
+		}
 		s.lastGCTime = time.Now()
 		s.resourceStateMu.Unlock()
 
 		s.wg.Go(s.resourceManager)
 
-		for i := 0; i < s.resourceSettings.MaxConcurrentRequests; i++ {
-			s.wg.Go(s.worker)
+		// Workers take requests from the dispatch queue if there is one, fed by dispatcher from the accept queue
+		workerQueue := s.requestQueue
+		if s.dispatchQueue != nil {
+			workerQueue = s.dispatchQueue
+			s.wg.Go(s.dispatcher)
+		}
+
+		workers := s.resourceSettings.MaxConcurrentRequests
+		for i := range workers {
+			readyAt := s.startTime.Add(s.workerWarmupDelay(i, workers))
+			s.wg.Go(func() { s.worker(readyAt, workerQueue) })
 		}
 	}
 
-	s.startTime = time.Now()
 	return nil
 }
 
-// setupCurveFunctions initializes curve functions from behavior
+// SetBehaviorsDir sets the base directory for behavior script file references (empty disables them),
+// takes effect when the script is compiled next time
+func (s *Server) SetBehaviorsDir(dir string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviorsDir = dir
+}
+
+// restartScript replaces compiled behavior script with a fresh one from current behavior, must be called with mutex held.
+// Script is compiled only while server is running, so that its executor goroutine does not outlive the server
+func (s *Server) restartScript() {
+	if s.script != nil {
+		s.script.Close()
+		s.script = nil
+	}
+
+	if !s.running.Load() || strings.TrimSpace(s.behavior.Script) == "" {
+		return
+	}
+
+	source, err := resolveBehavior(s.behaviorsDir, s.behavior.Script)
+	if err != nil {
+		log.Printf("Server: Error: Behavior script: %v, using curves only", err)
+		return
+	}
+	script, err := NewStarlarkServerBehavior(source)
+	if err != nil {
+		log.Printf("Server: Error: Behavior script: %v, using curves only", err)
+		return
+	}
+	s.script = script
+}
+
+// setupCurveFunctions initializes curve functions from behavior, stretching time axis by time scale
 func (s *Server) setupCurveFunctions() {
 	behavior := s.behavior
+	maxX := float64(behavior.To) * 1000 * s.timeScale
 	s.getErrorRate = CurveFunction(
 		0,
-		float64(behavior.To)*1000,
+		maxX,
 		0,
 		1,
 		behavior.Errors,
 	)
 	s.getResponseTimeMin = CurveFunction(
 		0,
-		float64(behavior.To)*1000,
+		maxX,
 		float64(behavior.ResponseTimeFrom),
 		float64(behavior.ResponseTimeTo),
 		behavior.ResponseTimeMin,
 	)
 	s.getResponseTimeMax = CurveFunction(
 		0,
-		float64(behavior.To)*1000,
+		maxX,
 		float64(behavior.ResponseTimeFrom),
 		float64(behavior.ResponseTimeTo),
 		behavior.ResponseTimeMax,
@@ -200,8 +386,62 @@ func (s *Server) resourceManager() {
 	}
 }
 
-// worker processes requests from the queue
-func (s *Server) worker() {
+// nextSharedLatencyNoise updates and returns the shared latency noise, must be called with mutex held.
+// Noise follows Ornstein-Uhlenbeck process, which stays standard normal and decorrelates over sharedLatencyNoiseTime.
+func (s *Server) nextSharedLatencyNoise(latencyCorrelation float64) float64 {
+	if latencyCorrelation <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	if s.sharedLatencyNoiseAt.IsZero() {
+		s.sharedLatencyNoise = rand.NormFloat64()
+	} else {
+		decay := math.Exp(-float64(now.Sub(s.sharedLatencyNoiseAt)) / float64(sharedLatencyNoiseTime))
+		s.sharedLatencyNoise = s.sharedLatencyNoise*decay + math.Sqrt(1-decay*decay)*rand.NormFloat64()
+	}
+	s.sharedLatencyNoiseAt = now
+
+	return s.sharedLatencyNoise
+}
+
+// capacityWarmup returns fraction of CapacityRPS available at the given time, ramping linearly during warmup.
+// Fraction never drops below one worker's share (minCapacityWarmupFraction without a worker pool), so that requests
+// arriving right after start are not served at nearly zero rate. Must be called with mutex held
+func (s *Server) capacityWarmup() func(at time.Time) float64 {
+	warmup := s.behavior.CapacityWarmupSec
+	fraction := min(max(s.behavior.CapacityWarmupFraction, 0), 1)
+	if workers := s.resourceSettings.MaxConcurrentRequests; s.behavior.EnableResourceManagement && workers > 0 {
+		fraction = max(fraction, 1/float64(workers))
+	} else {
+		fraction = max(fraction, minCapacityWarmupFraction)
+	}
+	startTime := s.startTime
+	return func(at time.Time) float64 {
+		elapsed := at.Sub(startTime).Seconds()
+		if warmup <= 0 || elapsed >= warmup {
+			return 1
+		}
+		return fraction + (1-fraction)*max(elapsed, 0)/warmup
+	}
+}
+
+// workerWarmupDelay returns time after start when worker with the given index joins the pool during capacity warmup,
+// the first worker is always available immediately
+func (s *Server) workerWarmupDelay(index, workers int) time.Duration {
+	warmup := s.behavior.CapacityWarmupSec
+	fraction := min(max(s.behavior.CapacityWarmupFraction, 0), 1)
+	if warmup <= 0 || fraction >= 1 {
+		return 0
+	}
+	// Worker joins when available share of workers exceeds its index
+	delaySec := warmup * (float64(index)/float64(workers) - fraction) / (1 - fraction)
+	return time.Duration(max(delaySec, 0) * float64(time.Second))
+}
+
+// dispatcher moves accepted requests from the accept queue to the dispatch queue,
+// rejecting them if the dispatch queue is full
+func (s *Server) dispatcher() {
 	for {
 		select {
 		case <-s.ctx.Done():
@@ -212,6 +452,41 @@ func (s *Server) worker() {
 				return
 			}
 
+			select {
+			case s.dispatchQueue <- queuedReq:
+				// Successfully dispatched
+			case <-s.ctx.Done():
+				close(queuedReq.Response)
+				return
+			default:
+				// Dispatch queue is full, reject already accepted request
+				s.countReceived(queuedReq.Request, &s.metrics.ServerDispatchRejectedRequests)
+				queuedReq.Response <- QueuedResponse{
+					Response: s.rejectedResponse(queuedReq.Request),
+					Error:    fmt.Errorf("server dispatch queue full"),
+				}
+				close(queuedReq.Response)
+			}
+		}
+	}
+}
+
+// worker processes requests from the queue, starting at readyAt
+func (s *Server) worker(readyAt time.Time, queue <-chan QueuedRequest) {
+	if err := SleepWithContext(s.ctx, time.Until(readyAt)); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+
+		case queuedReq, ok := <-queue:
+			if !ok {
+				return
+			}
+
 			// Check context before processing
 			select {
 			case <-s.ctx.Done():
@@ -221,14 +496,16 @@ func (s *Server) worker() {
 			}
 
 			// Increment active requests
+			cpuCost := queuedReq.Request.cpuWeight()
 			s.resourceStateMu.Lock()
 			s.resourceState.ActiveRequests++
+			s.resourceState.ActiveCPUCost += cpuCost
 			s.resourceStateMu.Unlock()
 
 			queueTime := time.Since(queuedReq.QueuedAt)
 			s.updateQueueMetrics(queueTime.Seconds() * 1000)
 
-			response, err := s.processRequest(queuedReq.Request, true)
+			response, err := s.processRequest(queuedReq.Request, true, queuedReq.QueueDepth)
 
 			// Try to send response
 			select {
@@ -240,6 +517,7 @@ func (s *Server) worker() {
 			// Decrement active requests
 			s.resourceStateMu.Lock()
 			s.resourceState.ActiveRequests--
+			s.resourceState.ActiveCPUCost -= cpuCost
 			s.resourceStateMu.Unlock()
 		}
 	}
@@ -285,9 +563,13 @@ func (s *Server) updateResources() {
 	// It grows faster as we approach capacity (non-linear relationship)
 	loadFactor := s.resourceState.ThreadsUtilization
 
+	// IO-bound requests occupy threads, but do not burn CPU while waiting for IO,
+	// other requests burn CPU by their weight, so that a few heavy requests can saturate it
+	cpuLoadFactor := (s.resourceState.ActiveCPUCost - s.resourceState.IOCPUCost) / float64(maxReqs)
+
 	// CPU impact: starts slow, accelerates near capacity
 	// At 50% threads: ~35% CPU, at 75% threads: ~65% CPU, at 100% threads: ~100% CPU
-	targetCPU := math.Pow(loadFactor, 1.5) * 0.95 // Power function for non-linear growth
+	targetCPU := math.Pow(max(cpuLoadFactor, 0), 1.5) * 0.95 // Power function for non-linear growth
 
 	// Smooth transition using exponential moving average
 	smoothingFactor := 0.3
@@ -301,12 +583,15 @@ func (s *Server) updateResources() {
 		s.resourceState.CPUUtilization = 0.0
 	}
 
-	// Memory calculation: base memory + (active requests * per-request memory) + accumulated leaks
+	// Memory calculation: base memory + (active requests * per-request memory) + buffered responses + accumulated leaks
 	baseMemoryMB := float64(maxReqs) * 0.5 // Base memory for server infrastructure
 	requestMemoryMB := float64(activeReqs) * s.resourceSettings.MemoryPerRequestMB
+	responseBytes := s.responseBytesInFlight.Load()
+	responseMemoryMB := float64(responseBytes) / 1024 * s.resourceSettings.MemoryPerResponseKB / 1024
+	s.resourceState.ResponseBytesInFlight = responseBytes
 
-	// Calculate target memory (base + requests)
-	targetMemoryMB := baseMemoryMB + requestMemoryMB
+	// Calculate target memory (base + requests + responses)
+	targetMemoryMB := baseMemoryMB + requestMemoryMB + responseMemoryMB
 
 	// Add memory leak over time (only when under load)
 	if loadFactor > 0.1 {
@@ -352,6 +637,19 @@ func (s *Server) updateResources() {
 	queueCapacity := cap(s.requestQueue)
 	s.resourceState.QueueUtilization = float64(queuedRequests) / float64(queueCapacity)
 
+	// Dispatch queue utilization
+	dispatchQueued := len(s.dispatchQueue)
+	s.resourceState.DispatchQueued = int64(dispatchQueued)
+	s.resourceState.DispatchQueueUtilization = 0
+	if dispatchCapacity := cap(s.dispatchQueue); dispatchCapacity > 0 {
+		s.resourceState.DispatchQueueUtilization = float64(dispatchQueued) / float64(dispatchCapacity)
+	}
+
+	// IO pool utilization
+	if ioCapacity := cap(s.ioSemaphore); ioCapacity > 0 {
+		s.resourceState.IOUtilization = float64(s.resourceState.ActiveIO) / float64(ioCapacity)
+	}
+
 	// Push latest resource state to metrics
 	if s.metrics != nil {
 		s.metrics.SetResourceState(ResourceMetrics{
@@ -363,6 +661,13 @@ func (s *Server) updateResources() {
 			ThreadsUtilization: s.resourceState.ThreadsUtilization,
 			AverageQueueTimeMs: s.resourceState.AverageQueueTimeMs,
 			MaxQueueTimeMs:     s.resourceState.MaxQueueTimeMs,
+			ActiveIO:           s.resourceState.ActiveIO,
+			IOUtilization:      s.resourceState.IOUtilization,
+
+			DispatchQueuedRequests:   s.resourceState.DispatchQueued,
+			DispatchQueueUtilization: s.resourceState.DispatchQueueUtilization,
+
+			ResponseBytesInFlight: s.resourceState.ResponseBytesInFlight,
 		})
 	}
 }
@@ -372,24 +677,39 @@ func (s *Server) getResourceImpact() (responseTimeMultiplier float64, additional
 	s.resourceStateMu.RLock()
 	defer s.resourceStateMu.RUnlock()
 
-	responseTimeMultiplier = 1.0
+	cpuWeight, memWeight, threadsWeight := s.resourceImpactWeights()
+	var cpuImpact, memImpact, concurrencyImpact float64
 
 	// CPU impact - exponential degradation when high
 	if s.resourceState.CPUUtilization > 0.7 {
-		cpuImpact := math.Pow(s.resourceState.CPUUtilization, 3)
-		responseTimeMultiplier *= (1.0 + cpuImpact*2)
+		cpuImpact = math.Pow(s.resourceState.CPUUtilization, 3) * 2
 	}
 
 	// Memory pressure impact - when memory is scarce, everything slows down
 	if s.resourceState.MemoryUtilization > 0.8 {
-		memImpact := (s.resourceState.MemoryUtilization - 0.8) / 0.2
-		responseTimeMultiplier *= (1.0 + memImpact*3)
+		memImpact = (s.resourceState.MemoryUtilization - 0.8) / 0.2 * 3
 	}
 
 	// Thread contention - when all workers are busy, there's context switching overhead
 	if s.resourceState.ThreadsUtilization > 0.7 {
-		concurrencyImpact := math.Pow(s.resourceState.ThreadsUtilization, 2)
-		responseTimeMultiplier *= (1.0 + concurrencyImpact)
+		concurrencyImpact = math.Pow(s.resourceState.ThreadsUtilization, 2)
+	}
+
+	if s.resourceSettings.AdditiveResourceImpact {
+		responseTimeMultiplier = 1.0 + cpuImpact*cpuWeight + memImpact*memWeight + concurrencyImpact*threadsWeight
+	} else {
+		responseTimeMultiplier = (1.0 + cpuImpact*cpuWeight) * (1.0 + memImpact*memWeight) * (1.0 + concurrencyImpact*threadsWeight)
+	}
+
+	// Swapping - past the threshold memory pages go to disk and everything is drastically slower, before OOM
+	if swap := s.resourceSettings.SwapThreshold; swap > 0 && swap < 1 && s.resourceSettings.SwapLatencyMultiplier > 0 &&
+		s.resourceState.MemoryUtilization > swap {
+		swapImpact := min((s.resourceState.MemoryUtilization-swap)/(1-swap), 1)
+		responseTimeMultiplier *= s.resourceSettings.SwapLatencyMultiplier * (1.0 + swapImpact)
+	}
+
+	if maxMultiplier := s.resourceSettings.MaxResourceImpactMultiplier; maxMultiplier > 0 {
+		responseTimeMultiplier = min(responseTimeMultiplier, maxMultiplier)
 	}
 
 	// Error rate increases under extreme resource pressure
@@ -404,6 +724,91 @@ func (s *Server) getResourceImpact() (responseTimeMultiplier float64, additional
 	return responseTimeMultiplier, additionalErrorRate
 }
 
+// resourceImpactWeights returns configured weights of CPU, memory and thread contention impacts,
+// equal weights when none is set (caller holds resourceStateMu)
+func (s *Server) resourceImpactWeights() (cpu, memory, threads float64) {
+	rs := s.resourceSettings
+	if rs.CPUImpactWeight == 0 && rs.MemoryImpactWeight == 0 && rs.ThreadsImpactWeight == 0 {
+		return 1, 1, 1
+	}
+	return rs.CPUImpactWeight, rs.MemoryImpactWeight, rs.ThreadsImpactWeight
+}
+
+// getQueueDepthImpact calculates response time multiplier caused by contention with requests queued ahead
+func (s *Server) getQueueDepthImpact(queueDepth int) float64 {
+	s.resourceStateMu.RLock()
+	defer s.resourceStateMu.RUnlock()
+
+	return 1.0 + s.resourceSettings.QueueDepthLatencyFactor*float64(queueDepth)
+}
+
+// isIOBound randomly decides whether request is IO-bound, according to the configured IO-bound fraction
+func (s *Server) isIOBound(ioSemaphore chan struct{}) bool {
+	if ioSemaphore == nil {
+		return false
+	}
+
+	s.resourceStateMu.RLock()
+	ioBoundFraction := s.resourceSettings.IOBoundFraction
+	s.resourceStateMu.RUnlock()
+
+	return ioBoundFraction > 0 && rand.Float64() < ioBoundFraction
+}
+
+// work spends the work duration, serial fraction of it is done holding the server-wide critical section,
+// so that with more concurrent requests they wait for each other and throughput stops growing
+func (s *Server) work(workDuration time.Duration, serialFraction float64) error {
+	if serialFraction <= 0 {
+		return SleepWithContext(s.ctx, workDuration)
+	}
+
+	serialDuration := time.Duration(float64(workDuration) * serialFraction)
+	err := SleepWithContext(s.ctx, workDuration-serialDuration)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case s.criticalSection <- struct{}{}:
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+	defer func() { <-s.criticalSection }()
+
+	return SleepWithContext(s.ctx, serialDuration)
+}
+
+// acquireIO waits for a free slot in the IO pool, and returns function to release it
+func (s *Server) acquireIO(ioSemaphore chan struct{}, cpuCost float64) (release func(), err error) {
+	s.resourceStateMu.Lock()
+	s.resourceState.IORequests++
+	s.resourceState.IOCPUCost += cpuCost
+	s.resourceStateMu.Unlock()
+
+	select {
+	case ioSemaphore <- struct{}{}:
+	case <-s.ctx.Done():
+		s.resourceStateMu.Lock()
+		s.resourceState.IORequests--
+		s.resourceState.IOCPUCost -= cpuCost
+		s.resourceStateMu.Unlock()
+		return nil, s.ctx.Err()
+	}
+
+	s.resourceStateMu.Lock()
+	s.resourceState.ActiveIO++
+	s.resourceStateMu.Unlock()
+
+	return func() {
+		<-ioSemaphore
+		s.resourceStateMu.Lock()
+		s.resourceState.ActiveIO--
+		s.resourceState.IORequests--
+		s.resourceState.IOCPUCost -= cpuCost
+		s.resourceStateMu.Unlock()
+	}, nil
+}
+
 // getGCPause checks if we're currently in a GC pause
 func (s *Server) getGCPause() float64 {
 	s.resourceStateMu.RLock()
@@ -417,35 +822,151 @@ func (s *Server) getGCPause() float64 {
 	return 0
 }
 
+// holdResponse accounts response buffered by the server until it is delivered, returns function to release it
+func (s *Server) holdResponse(sizeBytes int) (release func()) {
+	s.responseBytesInFlight.Add(int64(sizeBytes))
+	return func() {
+		s.responseBytesInFlight.Add(-int64(sizeBytes))
+	}
+}
+
 // HandleRequest routes to appropriate implementation based on resource management setting
 func (s *Server) HandleRequest(_unusedRequestCtx context.Context, req Request) (Response, error) {
 	s.mu.RLock()
 	enableResourceManagement := s.behavior.EnableResourceManagement
+	deprioritizeRetries := s.behavior.DeprioritizeRetries
+	idempotencyWindow := time.Duration(s.behavior.IdempotencyWindowMs) * time.Millisecond
+	singleFlight := s.behavior.SingleFlight
 	s.mu.RUnlock()
 
+	// Duplicate of already processed request: return original response without reprocessing
+	useIdempotency := idempotencyWindow > 0 && req.IdempotencyKey != ""
+	if useIdempotency {
+		if resp, found := s.getIdempotentResponse(req.IdempotencyKey); found {
+			s.countReceived(req, &s.metrics.ServerDeduplicatedRequests)
+			return resp, nil
+		}
+	}
+
+	// Request with the same key is already being processed: wait for it and share its result
+	var call *singleFlightCall
+	if singleFlight && req.Key != "" {
+		var leader bool
+		call, leader = s.joinSingleFlight(req.Key)
+		if !leader {
+			s.countReceived(req, &s.metrics.ServerCoalescedRequests)
+			<-call.done
+			resp := call.resp
+			resp.Id = req.Id
+			resp.TraceId = req.TraceId
+			return resp, call.err
+		}
+		defer s.finishSingleFlight(req.Key, call)
+	}
+
+	var resp Response
+	var err error
 	if enableResourceManagement {
-		return s.handleRequestWithResources(req)
+		resp, err = s.handleRequestWithResources(req, deprioritizeRetries)
+	} else {
+		// Simple mode: process directly without queue
+		resp, err = s.processRequest(req, false, 0)
+	}
+	if call != nil {
+		call.resp, call.err = resp, err
+	}
+
+	// Only successful responses are remembered, so that failed requests can be retried
+	if useIdempotency && err == nil && resp.Ok {
+		s.setIdempotentResponse(req.IdempotencyKey, resp, idempotencyWindow)
 	}
 
-	// Simple mode: process directly without queue
-	return s.processRequest(req, false)
+	return resp, err
+}
+
+// joinSingleFlight returns processing of the request with the key in progress, or starts a new one,
+// whose caller is the leader and has to finish it
+func (s *Server) joinSingleFlight(key string) (call *singleFlightCall, leader bool) {
+	s.singleFlightMu.Lock()
+	defer s.singleFlightMu.Unlock()
+
+	if call, found := s.singleFlightCalls[key]; found {
+		return call, false
+	}
+	call = &singleFlightCall{done: make(chan struct{})}
+	s.singleFlightCalls[key] = call
+	return call, true
 }
 
-// handleRequestWithResources implements queue-based processing with resource management
-func (s *Server) handleRequestWithResources(req Request) (Response, error) {
+// finishSingleFlight releases requests waiting for the processing, the next request with the key is processed anew
+func (s *Server) finishSingleFlight(key string, call *singleFlightCall) {
+	s.singleFlightMu.Lock()
+	delete(s.singleFlightCalls, key)
+	s.singleFlightMu.Unlock()
+	close(call.done)
+}
+
+// getIdempotentResponse returns the stored original response for the idempotency key, if it has not expired
+func (s *Server) getIdempotentResponse(key string) (Response, bool) {
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	entry, found := s.idempotencyCache[key]
+	if !found {
+		return Response{}, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.idempotencyCache, key)
+		return Response{}, false
+	}
+
+	return entry.response, true
+}
+
+// setIdempotentResponse stores the original response for the idempotency key, and sweeps expired entries once per window
+func (s *Server) setIdempotentResponse(key string, resp Response, window time.Duration) {
+	s.idempotencyMu.Lock()
+	defer s.idempotencyMu.Unlock()
+
+	now := time.Now()
+	s.idempotencyCache[key] = idempotencyEntry{
+		response:  resp,
+		expiresAt: now.Add(window),
+	}
+
+	if now.Sub(s.idempotencyLastSweep) > window {
+		s.idempotencyLastSweep = now
+		for k, entry := range s.idempotencyCache {
+			if now.After(entry.expiresAt) {
+				delete(s.idempotencyCache, k)
+			}
+		}
+	}
+}
+
+// handleRequestWithResources implements queue-based processing with resource management,
+// retries are rejected early if server deprioritizes them
+func (s *Server) handleRequestWithResources(req Request, deprioritizeRetries bool) (Response, error) {
 	// Check memory pressure before accepting request
 	s.resourceStateMu.RLock()
 	memUtil := s.resourceState.MemoryUtilization
 	s.resourceStateMu.RUnlock()
 
 	if memUtil > 0.98 {
-		return Response{}, fmt.Errorf("server out of memory")
+		return s.rejectedResponse(req), fmt.Errorf("server out of memory")
+	}
+
+	// Shed retries before the queue fills up, to keep room for first attempts
+	if deprioritizeRetries && req.IsRetry && float64(len(s.requestQueue)) >= float64(cap(s.requestQueue))*retryShedQueueUtilization {
+		s.countReceived(req, &s.metrics.ServerRetryShedRequests)
+		return s.rejectedResponse(req), fmt.Errorf("server shedding retries")
 	}
 
 	queuedReq := QueuedRequest{
-		Request:  req,
-		QueuedAt: time.Now(),
-		Response: make(chan QueuedResponse, 1),
+		Request:    req,
+		QueuedAt:   time.Now(),
+		QueueDepth: len(s.requestQueue),
+		Response:   make(chan QueuedResponse, 1),
 	}
 
 	// Check if server is shutting down
@@ -463,7 +984,8 @@ func (s *Server) handleRequestWithResources(req Request) (Response, error) {
 		return Response{}, s.ctx.Err()
 	default:
 		// Queue is full
-		return Response{}, fmt.Errorf("server queue full")
+		s.countReceived(req, &s.metrics.ServerQueueRejectedRequests)
+		return s.rejectedResponse(req), fmt.Errorf("server queue full")
 	}
 
 	// Wait for response
@@ -475,14 +997,48 @@ func (s *Server) handleRequestWithResources(req Request) (Response, error) {
 	}
 }
 
+// countReceived counts server-side outcome of the request, only if the request was counted as received,
+// so that recording resumed mid-request does not break counters balance, and probes are not counted
+func (s *Server) countReceived(req Request, counter *atomic.Int64) {
+	if req.received {
+		s.metrics.count(counter)
+	}
+}
+
+// rejectedResponse builds an error response for a request shed by the server, with a retry delay hint
+func (s *Server) rejectedResponse(req Request) Response {
+	return Response{
+		Id:           req.Id,
+		TraceId:      req.TraceId,
+		Outcome:      OutcomeRejected,
+		Ok:           false,
+		Timestamp:    time.Now(),
+		RetryAfterMs: s.getRetryAfterMs(),
+	}
+}
+
+// getRetryAfterMs suggests a retry delay from current queue time and utilization,
+// grows with queue wait time and with how saturated the queue and memory are
+func (s *Server) getRetryAfterMs() int {
+	s.resourceStateMu.RLock()
+	defer s.resourceStateMu.RUnlock()
+
+	state := s.resourceState
+	pressure := math.Max(state.QueueUtilization, state.MemoryUtilization)
+	retryAfterMs := math.Max(state.AverageQueueTimeMs, minRetryAfterMs) * (1 + pressure)
+
+	return int(math.Ceil(retryAfterMs))
+}
+
 // processRequest handles the actual request processing (used by both simple and resource modes)
-func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Response, error) {
+func (s *Server) processRequest(req Request, resourceManagementEnabled bool, queueDepth int) (Response, error) {
 	// Get resource impact if resource management is enabled
 	var responseTimeMultiplier float64 = 1.0
 	var additionalErrorRate float64 = 0.0
 
 	if resourceManagementEnabled {
 		responseTimeMultiplier, additionalErrorRate = s.getResourceImpact()
+		responseTimeMultiplier *= s.getQueueDepthImpact(queueDepth)
 	}
 
 	s.mu.Lock()
@@ -493,8 +1049,33 @@ func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Re
 	getErrorRate := s.getErrorRate
 	getResponseTimeMin := s.getResponseTimeMin
 	getResponseTimeMax := s.getResponseTimeMax
+	capacityRPS := s.behavior.CapacityRPS
+	capacityWarmup := s.capacityWarmup()
+	responseSize := s.behavior.ResponseSizeBytes
+	groupOverride, hasGroupOverride := s.behavior.GroupOverrides[req.Group]
+	fanOutBackends := s.behavior.FanOutBackends
+	fanOutBackendErrorRate := s.behavior.FanOutBackendErrorRate
+	fanOutQuorum := s.behavior.FanOutQuorum
+	latencyCorrelation := min(s.behavior.LatencyCorrelation, 1)
+	serialFraction := min(s.behavior.SerialFraction, 1)
+	truncateToRange := s.behavior.TruncateToRange
+	latencySpikes := s.behavior.LatencySpikes
+	timeScale := s.timeScale
+	responseTemplate := s.behavior.ResponseTemplate
+	if template, ok := s.behavior.ResponseTemplatesByKind[req.Kind]; ok && req.Kind != "" {
+		responseTemplate = template
+	}
+	sharedNoise := s.nextSharedLatencyNoise(latencyCorrelation)
+	ioSemaphore := s.ioSemaphore
+	script := s.script
 	s.mu.Unlock()
 
+	// Behavior script decides on the request before curve-based timing
+	var scripted ServerScriptResult
+	if script != nil {
+		scripted = script.OnHandle(&req)
+	}
+
 	elapsedMs := float64(time.Since(behaviorStartTime).Milliseconds())
 
 	responseTimeMin := getResponseTimeMin(elapsedMs)
@@ -507,27 +1088,66 @@ func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Re
 	}
 
 	var workMs float64
-	if min == max {
+	if capacityRPS > 0 {
+		// Capacity mode replaces curve-sampled work with time in the virtual capacity queue, including service
+		workMs = s.reserveCapacity(capacityRPS, capacityWarmup).Seconds() * 1000
+	} else if min == max {
 		workMs = min
 	} else {
 		mean := (min + max) / 2
 		stddev := (max - min) / 6
-		workMs = rand.NormFloat64()*stddev + mean
+		noise := rand.NormFloat64()
+		if latencyCorrelation > 0 {
+			// Mix shared and own noise keeping unit variance, so that concurrent requests are correlated by the given share
+			noise = math.Sqrt(latencyCorrelation)*sharedNoise + math.Sqrt(1-latencyCorrelation)*noise
+		}
+		workMs = noise*stddev + mean
+		if truncateToRange {
+			// Clamp rather than re-sample, so that correlation with shared noise is kept
+			workMs = math.Min(math.Max(workMs, min), max)
+		}
 		if workMs < 0 {
 			workMs = 0
 		}
 	}
+	if scripted.HasWorkMs {
+		workMs = scripted.WorkMs
+	}
 
 	// Apply resource impact if resource management is enabled
 	workMs *= responseTimeMultiplier
 
+	if hasGroupOverride {
+		workMs *= groupOverride.ResponseTimeMultiplier
+	}
+
+	// Batched request carries work of all its logical operations
+	if req.BatchSize > 1 {
+		workMs *= float64(req.BatchSize)
+	}
+
 	if resourceManagementEnabled {
 		workMs += s.getGCPause()
 	}
 
+	workMs += scripted.ExtraMs
+	workMs += spikeExtraMs(latencySpikes, SpikeAll, elapsedMs, timeScale)
+	if workMs < 0 {
+		workMs = 0
+	}
+
 	workDuration := time.Duration(workMs * float64(time.Millisecond))
 
-	err := SleepWithContext(s.ctx, workDuration)
+	// IO-bound requests have to hold a slot in the IO pool for the duration of work
+	if resourceManagementEnabled && s.isIOBound(ioSemaphore) {
+		release, err := s.acquireIO(ioSemaphore, req.cpuWeight())
+		if err != nil {
+			return Response{}, err
+		}
+		defer release()
+	}
+
+	err := s.work(workDuration, serialFraction)
 	if err != nil {
 		return Response{}, err
 	}
@@ -535,6 +1155,9 @@ func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Re
 	// Check for errors
 	baseErrorRate := getErrorRate(elapsedMs)
 	totalErrorRate := baseErrorRate + additionalErrorRate
+	if hasGroupOverride {
+		totalErrorRate *= groupOverride.ErrorRateMultiplier
+	}
 	if totalErrorRate > 1.0 {
 		totalErrorRate = 1.0
 	}
@@ -542,6 +1165,8 @@ func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Re
 	if totalErrorRate > 0 && rand.Float64() < totalErrorRate {
 		errResp := Response{
 			Id:        req.Id,
+			TraceId:   req.TraceId,
+			Outcome:   OutcomeServerError,
 			Ok:        false,
 			Error:     "Server Error",
 			Timestamp: time.Now(),
@@ -549,16 +1174,121 @@ func (s *Server) processRequest(req Request, resourceManagementEnabled bool) (Re
 		return errResp, fmt.Errorf("server error")
 	}
 
+	if scripted.Error {
+		errResp := Response{
+			Id:        req.Id,
+			TraceId:   req.TraceId,
+			Outcome:   OutcomeServerError,
+			Ok:        false,
+			Error:     scripted.ErrorMessage,
+			Timestamp: time.Now(),
+		}
+		return errResp, fmt.Errorf("server error by behavior script")
+	}
+
+	// Scatter-gather: request fails only if not enough backends responded
+	if fanOutBackends > 0 && !s.fanOutSucceeded(req, fanOutBackends, fanOutBackendErrorRate, fanOutQuorum) {
+		errResp := Response{
+			Id:        req.Id,
+			TraceId:   req.TraceId,
+			Outcome:   OutcomeServerError,
+			Ok:        false,
+			Error:     "Fan-out Quorum Not Reached",
+			Timestamp: time.Now(),
+		}
+		return errResp, fmt.Errorf("server fan-out quorum not reached")
+	}
+
 	resp := Response{
 		Id:        req.Id,
+		TraceId:   req.TraceId,
+		Outcome:   OutcomeSuccess,
 		Ok:        true,
-		Data:      "OK",
+		Data:      renderResponseTemplate(responseTemplate, req, elapsedMs),
 		Timestamp: time.Now(),
 	}
+	resp.SizeBytes = len(resp.Data)
+	if responseSize > 0 {
+		resp.SizeBytes = responseSize
+	}
+	if req.BatchSize > 1 {
+		resp.SizeBytes *= req.BatchSize
+	}
 
 	return resp, nil
 }
 
+// renderResponseTemplate returns response body by the template with request fields substituted, "OK" if template is empty
+func renderResponseTemplate(template string, req Request, elapsedMs float64) string {
+	if template == "" {
+		return "OK"
+	}
+	return strings.NewReplacer(
+		"{request_id}", req.Id,
+		"{trace_id}", req.TraceId,
+		"{client_id}", req.ClientId,
+		"{group}", req.Group,
+		"{kind}", req.Kind,
+		"{elapsed_ms}", strconv.FormatFloat(elapsedMs, 'f', -1, 64),
+	).Replace(template)
+}
+
+// fanOutSucceeded samples outcomes of backend calls and decides whether the quorum is reached,
+// counting requests which succeeded with some of the backends failed, and requests which failed by quorum
+func (s *Server) fanOutSucceeded(req Request, backends int, backendErrorRate float64, quorum int) bool {
+	if quorum <= 0 || quorum > backends {
+		quorum = backends
+	}
+
+	failed := 0
+	for range backends {
+		if backendErrorRate > 0 && rand.Float64() < backendErrorRate {
+			failed++
+		}
+	}
+
+	if backends-failed < quorum {
+		s.countReceived(req, &s.metrics.ServerFanOutFailedRequests)
+		return false
+	}
+	if failed > 0 {
+		s.countReceived(req, &s.metrics.ServerFanOutPartialRequests)
+	}
+	return true
+}
+
+// reserveCapacity schedules request in a virtual single-server FIFO queue served at the given rate with
+// exponentially distributed service times (M/M/1-style), and returns the request's time in the queue, including service.
+// Time grows as 1/(1-load) when offered load approaches capacity, and without bound while load exceeds it.
+// Service runs at the capacity available by warmup when it starts, so queue built up early drains once warmup ends
+func (s *Server) reserveCapacity(capacityRPS float64, warmup func(at time.Time) float64) time.Duration {
+	s.capacityMu.Lock()
+	defer s.capacityMu.Unlock()
+
+	now := time.Now()
+	start := s.capacityBusyUntil
+	if start.Before(now) {
+		start = now
+	}
+	serviceTime := time.Duration(rand.ExpFloat64() / (capacityRPS * warmup(start)) * float64(time.Second))
+	s.capacityBusyUntil = start.Add(serviceTime)
+
+	return s.capacityBusyUntil.Sub(now)
+}
+
+// SampleCurves evaluates behavior curves over the behavior time range, keyed by curve name
+func (s *Server) SampleCurves(resolution int) map[string][]CurveSample {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	maxX := float64(s.behavior.To) * 1000 * s.timeScale
+	return map[string][]CurveSample{
+		"server_errors": SampleCurve(s.getErrorRate, maxX, resolution),
+		"server_rt_min": SampleCurve(s.getResponseTimeMin, maxX, resolution),
+		"server_rt_max": SampleCurve(s.getResponseTimeMax, maxX, resolution),
+	}
+}
+
 // GetBehavior returns the current server behavior
 func (s *Server) GetBehavior() ServerBehavior {
 	s.mu.RLock()
@@ -566,17 +1296,35 @@ func (s *Server) GetBehavior() ServerBehavior {
 	return s.behavior
 }
 
-// SetBehavior sets the current server behavior
+// SetBehavior sets the current server behavior, cancelling ongoing transition
 func (s *Server) SetBehavior(behavior ServerBehavior) {
 	s.mu.Lock()
 	s.resourceStateMu.Lock()
 	defer s.mu.Unlock()
 	defer s.resourceStateMu.Unlock()
 
+	s.cancelTransition()
 	s.behavior = behavior
 	s.resourceSettings = behavior.ResourceSettings
 	s.behaviorStartTime = time.Time{}
 	s.setupCurveFunctions()
+	s.restartScript()
+
+	s.capacityMu.Lock()
+	s.capacityBusyUntil = time.Time{}
+	s.capacityMu.Unlock()
+
+	s.idempotencyMu.Lock()
+	clear(s.idempotencyCache)
+	s.idempotencyMu.Unlock()
+}
+
+// SetTimeScale stretches (or shrinks) the behavior time axis by the given factor, without changing the behavior
+func (s *Server) SetTimeScale(timeScale float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeScale = timeScale
+	s.setupCurveFunctions()
 }
 
 // ResetBehavior resets the behavior of the server to its initial state
@@ -594,4 +1342,8 @@ func (s *Server) Shutdown() {
 		s.cancel()
 	}
 	s.wg.Wait()
+
+	s.mu.Lock()
+	s.restartScript() // Not running, only closes the script
+	s.mu.Unlock()
 }
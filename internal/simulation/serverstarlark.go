@@ -0,0 +1,197 @@
+package simulation
+
+import (
+	"fmt"
+	"log"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+type serverScriptExecution struct {
+	req      *Request
+	resultCh chan ServerScriptResult
+}
+
+// ServerScriptResult is what `on_handle` hook decided about the request
+type ServerScriptResult struct {
+	WorkMs       float64 // Work time which replaces the one sampled from response time curves (if HasWorkMs)
+	HasWorkMs    bool
+	ExtraMs      float64 // Work time added after all multipliers
+	Error        bool    // Request fails with server error, regardless of the error rate
+	ErrorMessage string
+}
+
+// StarlarkServerBehavior runs `on_handle(req)` hook of server behavior script for every processed request.
+// Like client behavior, hooks are executed one at a time by a single executor goroutine, which owns the script state
+type StarlarkServerBehavior struct {
+	globals       starlark.StringDict
+	setState      starlark.Callable
+	onHandle      starlark.Callable
+	executionChan chan *serverScriptExecution
+	stopChan      chan struct{}
+}
+
+// maxHandleSteps limits execution of script loading and of each hook call, so that a looping script
+// fails the call instead of blocking every request
+const maxHandleSteps = 10_000_000
+
+// NewStarlarkServerBehavior loads the Starlark script, extracts `on_handle` hook and starts the executor goroutine
+func NewStarlarkServerBehavior(script string) (*StarlarkServerBehavior, error) {
+	thread := &starlark.Thread{Name: "server-compiler"}
+	thread.SetMaxExecutionSteps(maxHandleSteps)
+	options := &syntax.FileOptions{}
+
+	globals, err := starlark.ExecFileOptions(options, thread, "server_behavior.star", script, globalStarlarkBuiltins)
+	if err != nil {
+		return nil, fmt.Errorf("starlark script error: %v", err)
+	}
+
+	getFn := func(name string) starlark.Callable {
+		if fn, ok := globals[name]; ok {
+			if fn, ok := fn.(starlark.Callable); ok {
+				return fn
+			}
+		}
+		return nil
+	}
+
+	behavior := &StarlarkServerBehavior{
+		globals:       globals,
+		setState:      getFn("set_state"),
+		onHandle:      getFn("on_handle"),
+		executionChan: make(chan *serverScriptExecution, DefaultScriptQueueSize),
+		stopChan:      make(chan struct{}),
+	}
+
+	go behavior.scriptExecutor()
+
+	return behavior, nil
+}
+
+func (b *StarlarkServerBehavior) scriptExecutor() {
+	thread := &starlark.Thread{Name: "server-executor"}
+
+	// init "global" / thread local state for the script
+	if b.setState != nil {
+		limitSteps(thread, maxHandleSteps)
+		stateValue, err := starlark.Call(thread, b.setState, nil, nil)
+		if err != nil {
+			log.Printf("set_state error: %v\n", err)
+		} else {
+			thread.SetLocal(threadStateKey, stateValue)
+		}
+	}
+
+	for {
+		select {
+		case exec := <-b.executionChan:
+			exec.resultCh <- b.executeOnHandle(thread, exec.req)
+
+		case <-b.stopChan:
+			return
+		}
+	}
+}
+
+func (b *StarlarkServerBehavior) executeOnHandle(thread *starlark.Thread, req *Request) ServerScriptResult {
+	var result ServerScriptResult
+	if b.onHandle == nil {
+		return result
+	}
+
+	reqDict := requestToDict(req)
+	reqDict.SetKey(starlark.String("group"), starlark.String(req.Group))
+	reqDict.SetKey(starlark.String("batch_size"), starlark.MakeInt(req.BatchSize))
+	limitSteps(thread, maxHandleSteps)
+	starlarkResult, err := starlark.Call(thread, b.onHandle, starlark.Tuple{reqDict}, nil)
+	if err != nil {
+		log.Printf("Error evaluating server behavior (trace %s): on_handle error: %v", req.TraceId, err)
+		return result
+	}
+
+	return parseOnHandleResult(starlarkResult)
+}
+
+// limitSteps allows the thread to execute up to steps more, clearing cancellation by previously exceeded limit,
+// so that every hook call gets its own budget on the long-living executor thread
+func limitSteps(thread *starlark.Thread, steps uint64) {
+	thread.Uncancel()
+	thread.SetMaxExecutionSteps(thread.ExecutionSteps() + steps)
+}
+
+func (b *StarlarkServerBehavior) Close() {
+	close(b.stopChan)
+}
+
+// HasHandleHook returns whether the script defines `on_handle` hook
+func (b *StarlarkServerBehavior) HasHandleHook() bool {
+	return b.onHandle != nil
+}
+
+// Call `on_handle` hook, returns empty result (no changes) if behavior is closed
+func (b *StarlarkServerBehavior) OnHandle(req *Request) ServerScriptResult {
+	resultCh := make(chan ServerScriptResult, 1)
+	exec := &serverScriptExecution{
+		req:      req,
+		resultCh: resultCh,
+	}
+
+	select {
+	case b.executionChan <- exec:
+		// Successfully queued
+	case <-b.stopChan:
+		return ServerScriptResult{}
+	}
+
+	select {
+	case result := <-resultCh:
+		return result
+	case <-b.stopChan:
+		return ServerScriptResult{}
+	}
+}
+
+// parseOnHandleResult reads `work_ms`, `extra_ms`, `error` and `error_code` from hook result dict,
+// `error` is either a flag or an error message, and `error_code` alone fails request as well
+func parseOnHandleResult(value starlark.Value) ServerScriptResult {
+	var result ServerScriptResult
+
+	dict, ok := value.(*starlark.Dict)
+	if !ok {
+		return result
+	}
+
+	if v, found, _ := dict.Get(starlark.String("work_ms")); found {
+		if f, ok := starlark.AsFloat(v); ok {
+			result.WorkMs = max(f, 0)
+			result.HasWorkMs = true
+		}
+	}
+	if v, found, _ := dict.Get(starlark.String("extra_ms")); found {
+		if f, ok := starlark.AsFloat(v); ok {
+			result.ExtraMs = f
+		}
+	}
+
+	result.ErrorMessage = "Server Error"
+	if v, found, _ := dict.Get(starlark.String("error")); found {
+		if message, ok := v.(starlark.String); ok && message != "" {
+			result.Error = true
+			result.ErrorMessage = string(message)
+		} else {
+			result.Error = v.Truth() == starlark.True
+		}
+	}
+	if v, found, _ := dict.Get(starlark.String("error_code")); found {
+		if code, err := starlark.AsInt32(v); err == nil && code != 0 {
+			result.Error = true
+			result.ErrorMessage = fmt.Sprintf("%s (%d)", result.ErrorMessage, code)
+		}
+	}
+	if !result.Error {
+		result.ErrorMessage = ""
+	}
+
+	return result
+}
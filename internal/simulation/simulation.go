@@ -3,11 +3,19 @@ package simulation
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"maps"
 	"math/rand"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"go.starlark.net/starlark"
 )
 
 // Simulation manages the overall simulation including clients, network, and metrics
@@ -17,13 +25,112 @@ type Simulation struct {
 	network        *Network
 	clients        []*Client
 	clientsConfigs []ClientConfig
+	settings       Settings
+	seed           int64 // Seed of the current run, used to derive per-client random generators
+	behaviorsDir   string
+	scriptPool     *ScriptPool // Executors shared by behavior scripts of the current run, nil if every client has its own
 	metrics        *Metrics
+	timeSeries     *TimeSeries
+	timeline       *Timeline        // Request arrivals of the current (or last) run
+	replay         *RequestTimeline // Timeline replayed by clients instead of sending at request rate (nil if not set)
 	ctx            context.Context
 	cancel         context.CancelFunc
 	running        atomic.Bool
+	paused         atomic.Bool // Clients do not schedule new requests, shared with clients of the run
+	draining       atomic.Bool // Run is being drained, clients which are not started yet are not started anymore
+	abandoned      atomic.Bool // Set when previous run did not stop in time, simulation can not be started again
 	startedAt      atomic.Int64
 	wg             sync.WaitGroup
 	mu             sync.Mutex
+
+	prepared map[string][]*Client // Warm pool of clients created by Prepare for the next run, by group id
+
+	maxDuration      time.Duration             // Hard ceiling on run duration, run is stopped when reached (0 disables)
+	onMaxDuration    func(limit time.Duration) // Called after run is stopped by max duration (nil if not set)
+	maxDurationTimer *time.Timer               // Timer of the current run, nil if there is no ceiling or run is stopped
+}
+
+// DefaultMaxDuration is the default hard ceiling on run duration, so that forgotten runs do not consume resources indefinitely
+const DefaultMaxDuration = time.Hour
+
+// RampUpMode defines how clients of a group are started
+type RampUpMode int
+
+const (
+	RampUpGradual   RampUpMode = iota // Client starts are spread over ramp-up time, with jitter
+	RampUpImmediate                   // All clients are started right away, ignoring ramp-up time
+	RampUpHerd                        // All clients are prepared first and released at the same instant (thundering herd)
+)
+
+func (rm RampUpMode) String() string {
+	switch rm {
+	case RampUpGradual:
+		return "gradual"
+	case RampUpImmediate:
+		return "immediate"
+	case RampUpHerd:
+		return "herd"
+	default:
+		return "unknown"
+	}
+}
+
+// ArrivalModel defines how intervals between requests of a client are distributed around request rate
+type ArrivalModel int
+
+const (
+	ArrivalFixed   ArrivalModel = iota // Request rate with ±20% uniform jitter
+	ArrivalPoisson                     // Poisson process, exponentially distributed intervals with request rate mean
+)
+
+func (am ArrivalModel) String() string {
+	switch am {
+	case ArrivalFixed:
+		return "fixed"
+	case ArrivalPoisson:
+		return "poisson"
+	default:
+		return "unknown"
+	}
+}
+
+// DefaultMaxRetries is the number of retries per request made by built-in retry policy, if not set
+const DefaultMaxRetries = 3
+
+// RetryPolicy is the built-in retry decision, used when client behavior script has no `on_retry` hook
+type RetryPolicy struct {
+	RetryOnServerError  bool          // Retry error responses from server, including rejected requests
+	RetryOnNetworkError bool          // Retry timed out and dropped requests
+	MaxRetries          int           // Retries per request (DefaultMaxRetries if not positive)
+	RetryDelay          time.Duration // Delay before each retry, or base delay of backoff with jitter
+
+	JitterMode    RetryJitterMode // How retry delay is randomized and grows with attempts
+	MaxRetryDelay time.Duration   // Cap of backoff delay with jitter (0 is unlimited)
+}
+
+// RetryJitterMode defines backoff algorithm of built-in retry policy, as in "Exponential Backoff And Jitter" AWS article
+type RetryJitterMode int
+
+const (
+	RetryJitterNone         RetryJitterMode = iota // Constant retry delay, no backoff
+	RetryJitterFull                                // Random delay between 0 and exponential backoff
+	RetryJitterEqual                               // Half of exponential backoff, plus random delay up to the other half
+	RetryJitterDecorrelated                        // Random delay between base and 3 times the previous delay
+)
+
+func (jm RetryJitterMode) String() string {
+	switch jm {
+	case RetryJitterNone:
+		return "none"
+	case RetryJitterFull:
+		return "full"
+	case RetryJitterEqual:
+		return "equal"
+	case RetryJitterDecorrelated:
+		return "decorrelated"
+	default:
+		return "unknown"
+	}
 }
 
 // ClientConfig stores configuration for a group of clients
@@ -32,8 +139,99 @@ type ClientConfig struct {
 	Count       int
 	RequestRate time.Duration
 	RampUpTime  time.Duration
+	RampUpMode  RampUpMode
 	Delay       time.Duration
-	Behavior    string
+	Behavior    string // Behavior script source, or file reference prefixed with "@"
+	// Probability that the very first request of each client fails to connect
+	FirstRequestFailureRate float64
+	// Built-in retries, for clients without `on_retry` hook
+	RetryPolicy
+	// Pause of each client's requests after a failed request, independent of retries (0 disables)
+	ErrorCooldown time.Duration
+	// AIMD control of each client's request rate, starting from RequestRate
+	AdaptiveRate AdaptiveRate
+	// Number of logical operations (sent at RequestRate) batched into one request (0 or 1 disables)
+	BatchSize int
+	// Distribution of intervals between requests
+	ArrivalModel ArrivalModel
+	// Distribution of request body sizes, sampled per request (empty uses size of request data)
+	PayloadSizes []PayloadSize
+	// Open (request rate) or closed (fixed population waiting for responses) workload, and think time between
+	// outcome of a request and the next request of closed workload, distributed by arrival model
+	WorkloadModel WorkloadModel
+	ThinkTime     time.Duration
+	// Weighted kinds of requests, sampled per request (empty sends requests without kind)
+	RequestKinds []RequestKind
+	// Default client-side timeout of each request, if behavior does not set one (0 waits indefinitely),
+	// and its jitter in percent, sampled per request
+	Timeout          time.Duration
+	TimeoutJitterPct float64
+	// Number of consecutive failed requests after which a client abandons its session and stops (0 disables)
+	AbandonAfterFailures int
+	// Resolved behavior script source, loaded at start for reproducibility
+	BehaviorSource string
+}
+
+// RequestKind is a kind of request (like "read" or "write") with its relative frequency in the client group
+type RequestKind struct {
+	Name   string // Set as request kind, visible to client and server behavior scripts
+	Weight float64
+	Data   string // Request data of this kind (empty uses default data)
+
+	CPUCost float64 // CPU weight of requests of this kind relative to a default request (0 uses 1)
+}
+
+// WorkloadModel defines whether clients send requests regardless of previous ones, or wait for them
+type WorkloadModel int
+
+const (
+	WorkloadOpen   WorkloadModel = iota // Requests are sent at request rate, regardless of whether previous ones finished
+	WorkloadClosed                      // Each client waits for the outcome of its request, then thinks before sending the next one
+)
+
+func (wm WorkloadModel) String() string {
+	switch wm {
+	case WorkloadOpen:
+		return "open"
+	case WorkloadClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// PayloadSize is a request body size with its relative frequency in the distribution
+type PayloadSize struct {
+	SizeBytes int
+	Weight    float64
+}
+
+// stopTimeout bounds how long Stop waits for clients and server to finish before abandoning them
+const stopTimeout = 10 * time.Second
+
+// behaviorFilePrefix marks client behavior as a reference to a file in the behaviors directory
+const behaviorFilePrefix = "@"
+
+// Settings stores simulation-wide options
+type Settings struct {
+	StabilizationErrorRate float64 // Windowed error rate below which the run is considered stabilized
+	TimeScale              float64 // Multiplier for the time axis of server and network behaviors
+	Seed                   int64   // Seed for client jitter random generators, 0 picks a new seed on every run
+	StarvationThresholdSec float64 // Time without sent requests after which active client is counted as starved (0 disables)
+	ScriptQueueSize        int     // Number of hook calls which can wait for each client's behavior script executor
+	PercentileMethod       PercentileMethod
+
+	GoodputDeadlineMs int // Successful responses slower than this are not counted in goodput (0 counts all of them)
+
+	ScriptWorkers int // Number of goroutines shared by all clients' behavior scripts (0 runs one executor goroutine per client)
+
+	TimelineCapacity int // Maximum number of request arrivals captured from a run for replay (0 disables capture)
+
+	MetricsWindowMs int // Sliding window of response time and network latency metrics (0 uses 1 second)
+
+	SLOLatencyMs int // Response time objective, slower responses are counted as SLO violations (0 disables)
+
+	ApdexThresholdMs int // Apdex satisfied response time T, up to 4T is tolerating (0 disables the Apdex score)
 }
 
 // NewSimulation creates a new simulation with default settings
@@ -43,12 +241,90 @@ func NewSimulation(index int64) *Simulation {
 	server := NewServer(fmt.Sprintf("server-%d", index), metrics)
 	network := NewNetwork(server, metrics)
 
-	return &Simulation{
-		Id:      id,
-		server:  server,
-		network: network,
-		metrics: metrics,
+	s := &Simulation{
+		Id:         id,
+		server:     server,
+		network:    network,
+		metrics:    metrics,
+		timeSeries: NewTimeSeries(metrics),
+		timeline:   NewTimeline(),
+		settings: Settings{
+			StabilizationErrorRate: 0.05,
+			TimeScale:              1,
+			StarvationThresholdSec: 5,
+			ScriptQueueSize:        DefaultScriptQueueSize,
+		},
+
+		maxDuration: DefaultMaxDuration,
 	}
+
+	s.applySettings()
+
+	return s
+}
+
+// SetBehaviorsDir sets the base directory for behavior script file references (empty disables them)
+func (s *Simulation) SetBehaviorsDir(dir string) {
+	s.DiscardPrepared()
+	s.server.SetBehaviorsDir(dir)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.behaviorsDir = dir
+}
+
+// SetMaxDuration sets the hard ceiling on run duration (0 disables it), and callback called after run is stopped by it.
+// The ceiling is independent of any time limit of the caller, and takes effect on the next start
+func (s *Simulation) SetMaxDuration(limit time.Duration, onReached func(limit time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxDuration = limit
+	s.onMaxDuration = onReached
+}
+
+// GetSettings returns the current simulation settings
+func (s *Simulation) GetSettings() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settings
+}
+
+// SetSettings replaces the simulation settings
+func (s *Simulation) SetSettings(settings Settings) error {
+	if s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot update settings while running")
+	}
+	if settings.TimeScale <= 0 {
+		return fmt.Errorf("Simulation: Error: Time scale must be positive")
+	}
+	if settings.ScriptQueueSize <= 0 {
+		return fmt.Errorf("Simulation: Error: Script queue size must be positive")
+	}
+	if settings.ScriptWorkers < 0 {
+		return fmt.Errorf("Simulation: Error: Script workers must not be negative")
+	}
+
+	s.DiscardPrepared()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.settings = settings
+	s.applySettings()
+
+	return nil
+}
+
+// applySettings propagates settings to simulation components, must be called with mutex held
+func (s *Simulation) applySettings() {
+	s.metrics.SetStabilizationErrorRate(s.settings.StabilizationErrorRate)
+	s.metrics.SetPercentileMethod(s.settings.PercentileMethod)
+	s.metrics.SetStarvationThreshold(time.Duration(s.settings.StarvationThresholdSec * float64(time.Second)))
+	s.metrics.SetGoodputDeadline(time.Duration(s.settings.GoodputDeadlineMs) * time.Millisecond)
+	s.metrics.SetWindowDuration(time.Duration(s.settings.MetricsWindowMs) * time.Millisecond)
+	s.metrics.SetSLOLatency(time.Duration(s.settings.SLOLatencyMs) * time.Millisecond)
+	s.metrics.SetApdexThreshold(time.Duration(s.settings.ApdexThresholdMs) * time.Millisecond)
+	s.server.SetTimeScale(s.settings.TimeScale)
+	s.network.SetTimeScale(s.settings.TimeScale)
 }
 
 // IsRunning returns whether the simulation is currently running
@@ -56,6 +332,37 @@ func (s *Simulation) IsRunning() bool {
 	return s.running.Load()
 }
 
+// IsPaused returns whether the running simulation is paused
+func (s *Simulation) IsPaused() bool {
+	return s.running.Load() && s.paused.Load()
+}
+
+// Pause freezes request generation of clients without stopping them or resetting metrics,
+// requests in flight complete normally
+func (s *Simulation) Pause() error {
+	if !s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot pause, simulation is not running")
+	}
+	s.paused.Store(true)
+	log.Println("Simulation: Paused")
+	return nil
+}
+
+// Resume lets clients of the paused simulation schedule requests again
+func (s *Simulation) Resume() error {
+	if !s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot resume, simulation is not running")
+	}
+	s.paused.Store(false)
+	log.Println("Simulation: Resumed")
+	return nil
+}
+
+// IsAbandoned returns whether the last Stop did not complete in time and some goroutines were left running
+func (s *Simulation) IsAbandoned() bool {
+	return s.abandoned.Load()
+}
+
 // StartedAt returns the time when the simulation was started
 func (s *Simulation) StartedAt() int64 {
 	return s.startedAt.Load()
@@ -80,6 +387,15 @@ func (s *Simulation) SetServerBehavior(behavior ServerBehavior) {
 	}
 }
 
+// TransitionServerBehavior moves the server behavior to the target gradually over the given duration
+func (s *Simulation) TransitionServerBehavior(behavior ServerBehavior, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.server != nil {
+		s.server.TransitionBehavior(behavior, duration)
+	}
+}
+
 // ResetServerBehavior resets the server behavior state to default
 func (s *Simulation) ResetServerBehavior() {
 	s.mu.Lock()
@@ -108,6 +424,21 @@ func (s *Simulation) SetNetworkBehavior(behavior NetworkBehavior) {
 	}
 }
 
+// SampleCurves evaluates server and network behavior curves at resolution points each, keyed by curve name
+func (s *Simulation) SampleCurves(resolution int) map[string][]CurveSample {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	curves := make(map[string][]CurveSample)
+	if s.server != nil {
+		maps.Copy(curves, s.server.SampleCurves(resolution))
+	}
+	if s.network != nil {
+		maps.Copy(curves, s.network.SampleCurves(resolution))
+	}
+	return curves
+}
+
 // ResetNetworkBehavior resets the network behavior state to default
 func (s *Simulation) ResetNetworkBehavior() {
 	s.mu.Lock()
@@ -122,6 +453,144 @@ func (s *Simulation) GetMetricsSnapshot() map[string]any {
 	return s.metrics.GetSnapshot()
 }
 
+// GetMetricsCounters returns current values of metrics counters
+func (s *Simulation) GetMetricsCounters() map[string]int64 {
+	return s.metrics.GetCounters()
+}
+
+// CheckMetricsInvariants returns relationships between metrics counters which do not hold
+func (s *Simulation) CheckMetricsInvariants() []InvariantViolation {
+	return s.metrics.CheckInvariants()
+}
+
+// SetMetricsRecording turns metrics recording on or off, without affecting the simulation itself
+func (s *Simulation) SetMetricsRecording(recording bool) {
+	s.metrics.SetRecording(recording)
+}
+
+// IsMetricsRecording returns whether metrics are being recorded
+func (s *Simulation) IsMetricsRecording() bool {
+	return s.metrics.IsRecording()
+}
+
+// GetTimeline returns request arrivals captured from the current (or last) run
+func (s *Simulation) GetTimeline() RequestTimeline {
+	return s.timeline.GetTimeline()
+}
+
+// GetReplayTimeline returns the timeline replayed by clients, nil if clients send at request rate
+func (s *Simulation) GetReplayTimeline() *RequestTimeline {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replay
+}
+
+// SetReplayTimeline sets the timeline for the next runs to replay, instead of sending requests at request rate,
+// so that server and network behaviors are compared on exactly the same load (nil sends at request rate again)
+func (s *Simulation) SetReplayTimeline(timeline *RequestTimeline) error {
+	if s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot set replay timeline while running")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replay = timeline
+	return nil
+}
+
+// GetTimeSeries returns per-second rollup of the current (or last) run
+func (s *Simulation) GetTimeSeries() []TimeSeriesPoint {
+	return s.timeSeries.GetPoints()
+}
+
+// ProbeResult holds the outcome of a single synthetic request sent through the network
+type ProbeResult struct {
+	Request   Request
+	Response  Response
+	Error     error
+	Trace     SendTrace
+	TotalTime time.Duration
+}
+
+// Probe sends a single synthetic request through the network and server, bypassing clients and behaviors,
+// probe is not counted in run metrics and does not use or keep a client connection
+func (s *Simulation) Probe(ctx context.Context) (ProbeResult, error) {
+	if !s.running.Load() {
+		return ProbeResult{}, fmt.Errorf("Simulation: Error: Cannot probe while simulation is not running")
+	}
+
+	now := time.Now()
+	req := Request{
+		Id:        fmt.Sprintf("probe-%d", now.UnixNano()),
+		ClientId:  "probe",
+		TraceId:   NewTraceId(rand.New(rand.NewSource(now.UnixNano()))),
+		Data:      "test data",
+		Timestamp: now,
+		Meta:      starlark.NewDict(0),
+		probe:     true,
+	}
+
+	resp, trace, err := s.network.SendTraced(ctx, req)
+
+	return ProbeResult{
+		Request:   req,
+		Response:  resp,
+		Error:     err,
+		Trace:     trace,
+		TotalTime: time.Since(now),
+	}, nil
+}
+
+// BehaviorStatus describes behavior script a client was created with
+type BehaviorStatus struct {
+	Source   string // Resolved script source (empty if client has no script)
+	Hash     string // Short hash of the source, to tell scripts apart (empty if client has no script)
+	Compiled bool   // Script compiled, false if there is no script or client fell back to noop behavior
+	Error    string // Compile error, because of which client fell back to noop behavior
+}
+
+// ActiveBehavior is a behavior script in effect for live clients of a group
+type ActiveBehavior struct {
+	Group string
+	BehaviorStatus
+	Clients       int  // Number of live clients of the group using this script
+	ConfigChanged bool // Group configuration has a different script now (or group is deleted), it is used from the next run
+}
+
+// GetActiveBehaviors returns behavior scripts in effect for live clients, one entry per group and distinct script,
+// ordered by group
+func (s *Simulation) GetActiveBehaviors() []ActiveBehavior {
+	s.mu.Lock()
+	behaviorsDir := s.behaviorsDir
+	active := []ActiveBehavior{}
+	for _, client := range s.clients {
+		i := slices.IndexFunc(active, func(ab ActiveBehavior) bool {
+			return ab.Group == client.group && ab.BehaviorStatus == client.behaviorStatus
+		})
+		if i < 0 {
+			active = append(active, ActiveBehavior{Group: client.group, BehaviorStatus: client.behaviorStatus})
+			i = len(active) - 1
+		}
+		active[i].Clients++
+	}
+	s.mu.Unlock()
+
+	for i, ab := range active {
+		config, err := s.GetClientConfigById(ab.Group)
+		if err != nil {
+			active[i].ConfigChanged = true
+			continue
+		}
+		source, err := resolveBehavior(behaviorsDir, config.Behavior)
+		active[i].ConfigChanged = err != nil || behaviorHash(source) != ab.Hash
+	}
+
+	slices.SortStableFunc(active, func(a, b ActiveBehavior) int {
+		return strings.Compare(a.Group, b.Group)
+	})
+	return active
+}
+
 // GetClientConfigs returns the current client configurations
 func (s *Simulation) GetClientConfigs() []ClientConfig {
 	return s.clientsConfigs
@@ -138,21 +607,17 @@ func (s *Simulation) GetClientConfigById(id string) (ClientConfig, error) {
 }
 
 // UpdateClientConfig updates a client config by id
-func (s *Simulation) UpdateClientConfig(id string, count int, requestRate, rampUpTime, delay time.Duration, behavior string) error {
+func (s *Simulation) UpdateClientConfig(id string, config ClientConfig) error {
 	if s.running.Load() {
 		return fmt.Errorf("Simulation: Error: Cannot update client configs while running")
 	}
 
 	for i, cfg := range s.clientsConfigs {
 		if cfg.Id == id {
-			s.clientsConfigs[i] = ClientConfig{
-				Id:          id,
-				Count:       count,
-				RequestRate: requestRate,
-				RampUpTime:  rampUpTime,
-				Delay:       delay,
-				Behavior:    behavior,
-			}
+			config.Id = id
+			config.BehaviorSource = ""
+			s.clientsConfigs[i] = config
+			s.DiscardPrepared()
 			return nil
 		}
 	}
@@ -160,24 +625,36 @@ func (s *Simulation) UpdateClientConfig(id string, count int, requestRate, rampU
 	return fmt.Errorf("Client group with id '%s' not found", id)
 }
 
-// AddClientsConfig adds a client configuration without starting the clients
-func (s *Simulation) AddClientsConfig(id string, count int, requestRate, rampUpTime, delay time.Duration, behavior string) error {
+// AddClientsConfig adds a client configuration without starting the clients,
+// group id must be unique, next free "group-N" id is assigned if it is empty
+func (s *Simulation) AddClientsConfig(config ClientConfig) error {
 	if s.running.Load() {
 		return fmt.Errorf("Simulation: Error: Cannot add clients configs while running")
 	}
 
-	s.clientsConfigs = append(s.clientsConfigs, ClientConfig{
-		Id:          id,
-		Count:       count,
-		RequestRate: requestRate,
-		RampUpTime:  rampUpTime,
-		Delay:       delay,
-		Behavior:    behavior,
-	})
+	if config.Id == "" {
+		config.Id = s.nextClientConfigId()
+	} else if _, err := s.GetClientConfigById(config.Id); err == nil {
+		return fmt.Errorf("Client group with id '%s' already exists", config.Id)
+	}
+
+	config.BehaviorSource = ""
+	s.clientsConfigs = append(s.clientsConfigs, config)
+	s.DiscardPrepared()
 
 	return nil
 }
 
+// nextClientConfigId returns the first "group-N" id which is not taken by existing client groups
+func (s *Simulation) nextClientConfigId() string {
+	for n := len(s.clientsConfigs) + 1; ; n++ {
+		id := fmt.Sprintf("group-%d", n)
+		if _, err := s.GetClientConfigById(id); err != nil {
+			return id
+		}
+	}
+}
+
 // DeleteClientConfigById removes a client configuration by its Id
 func (s *Simulation) DeleteClientConfigById(id string) error {
 	if s.running.Load() {
@@ -186,6 +663,7 @@ func (s *Simulation) DeleteClientConfigById(id string) error {
 	for i, cfg := range s.clientsConfigs {
 		if cfg.Id == id {
 			s.clientsConfigs = append(s.clientsConfigs[:i], s.clientsConfigs[i+1:]...)
+			s.DiscardPrepared()
 			return nil
 		}
 	}
@@ -199,28 +677,128 @@ func (s *Simulation) ClearClientConfigs() error {
 	}
 
 	s.clientsConfigs = nil
+	s.DiscardPrepared()
+
+	return nil
+}
+
+// ReplaceConfig replaces client configurations, server and network behaviors all at once,
+// nothing is changed if simulation is running or client group ids are not unique
+func (s *Simulation) ReplaceConfig(configs []ClientConfig, server ServerBehavior, network NetworkBehavior) error {
+	if s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot replace configuration while running")
+	}
+
+	ids := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		if config.Id == "" {
+			continue
+		}
+		if ids[config.Id] {
+			return fmt.Errorf("Client group with id '%s' already exists", config.Id)
+		}
+		ids[config.Id] = true
+	}
+
+	s.clientsConfigs = nil
+	for _, config := range configs {
+		if config.Id == "" {
+			// Next free "group-N" id, which is not taken by any of the groups, including later ones
+			for n := len(s.clientsConfigs) + 1; config.Id == "" || ids[config.Id]; n++ {
+				config.Id = fmt.Sprintf("group-%d", n)
+			}
+			ids[config.Id] = true
+		}
+		config.BehaviorSource = ""
+		s.clientsConfigs = append(s.clientsConfigs, config)
+	}
+	s.DiscardPrepared()
+
+	s.SetServerBehavior(server)
+	s.SetNetworkBehavior(network)
 
 	return nil
 }
 
-// Start initializes and starts the simulation
-func (s *Simulation) Start() context.Context {
+// Start initializes and starts the simulation, returns nil context if simulation is already running
+func (s *Simulation) Start() (context.Context, error) {
+	if s.abandoned.Load() {
+		return nil, fmt.Errorf("Simulation: Error: Previous run did not stop cleanly, reset simulation to start again")
+	}
+
 	if !s.running.CompareAndSwap(false, true) {
-		return nil
+		return nil, nil
+	}
+	s.paused.Store(false)
+	s.draining.Store(false)
+
+	s.mu.Lock()
+	prepared := s.prepared
+	s.prepared = nil
+	replay := s.replay
+	s.mu.Unlock()
+
+	if prepared == nil {
+		err := s.prepareRun()
+		if err != nil {
+			s.running.Store(false)
+			return nil, err
+		}
 	}
 
-	log.Println("Simulation: Starting...")
+	log.Printf("Simulation: Starting with seed %d...\n", s.seed)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	s.ctx = ctx
 	s.cancel = cancel
 
-	s.startedAt.Store(time.Now().UnixMilli())
+	now := time.Now()
+	s.startedAt.Store(now.UnixMilli())
+	s.metrics.Start(now, now.Add(s.rampUpDuration(prepared != nil)), s.targetClients())
+
+	s.timeline.Start(now, s.settings.TimelineCapacity)
 
 	s.server.Start(ctx)
-	s.wg.Go(s.run)
+	if replay != nil {
+		s.wg.Go(func() { s.replayTimeline(prepared, replay, now) })
+	} else {
+		s.wg.Go(func() { s.run(prepared) })
+	}
+	s.wg.Go(func() { s.timeSeries.Run(ctx, now) })
+	s.wg.Go(func() { s.metrics.runInvariantChecks(ctx) })
+	s.startMaxDurationTimer()
+
+	return s.ctx, nil
+}
+
+// startMaxDurationTimer schedules stop of the current run when max duration is reached.
+// Timer could fire while the run is being stopped or restarted, so it stops simulation only if it is still the current timer
+func (s *Simulation) startMaxDurationTimer() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxDuration <= 0 {
+		return
+	}
+
+	limit := s.maxDuration
+	onReached := s.onMaxDuration
+	var timer *time.Timer
+	timer = time.AfterFunc(limit, func() {
+		s.mu.Lock()
+		current := s.maxDurationTimer == timer
+		s.mu.Unlock()
+		if !current {
+			return
+		}
 
-	return s.ctx
+		log.Printf("Simulation: Max duration (%v) reached, stopping", limit)
+		s.Stop()
+		if onReached != nil {
+			onReached(limit)
+		}
+	})
+	s.maxDurationTimer = timer
 }
 
 // Stop terminates the simulation
@@ -238,21 +816,250 @@ func (s *Simulation) Stop() {
 		s.wg.Go(client.Stop)
 	}
 	s.clients = nil
+	scriptPool := s.scriptPool
+	if s.maxDurationTimer != nil {
+		s.maxDurationTimer.Stop()
+		s.maxDurationTimer = nil
+	}
 	s.mu.Unlock()
 
-	s.server.Shutdown()
+	done := make(chan struct{})
+	go func() {
+		s.server.Shutdown()
+		s.wg.Wait()
+		if scriptPool != nil {
+			scriptPool.Stop()
+		}
+		close(done)
+	}()
 
-	s.wg.Wait()
+	select {
+	case <-done:
+	case <-time.After(stopTimeout):
+		// Some goroutine is stuck (e.g. behavior script never returns), give up waiting so that simulation can be reset
+		s.abandoned.Store(true)
+		buf := make([]byte, 1<<20)
+		n := runtime.Stack(buf, true)
+		log.Printf("Simulation: Warning: Stop did not complete in %v, abandoning remaining goroutines:\n%s", stopTimeout, buf[:n])
+	}
 
 	s.ResetServerBehavior()
 	s.ResetNetworkBehavior()
 }
 
-// run creates and starts all clients based on configurations
-func (s *Simulation) run() {
-	for groupIndex, config := range s.clientsConfigs {
+// StopDrain stops the run gracefully: clients stop scheduling new requests, not yet started clients are not started,
+// and in-flight requests complete with their retries for up to timeout, then the run is stopped by Stop, aborting the rest
+func (s *Simulation) StopDrain(timeout time.Duration) {
+	if !s.running.Load() || !s.draining.CompareAndSwap(false, true) {
+		return
+	}
+
+	log.Printf("Simulation: Draining clients for up to %v...", timeout)
+
+	s.mu.Lock()
+	clients := slices.Clone(s.clients)
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(timeout)
+	var wg sync.WaitGroup
+	var aborted atomic.Int64
+	for _, client := range clients {
+		wg.Go(func() {
+			if !client.Drain(time.Until(deadline)) {
+				aborted.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := aborted.Load(); n > 0 {
+		log.Printf("Simulation: Warning: %d clients did not drain in %v, their in-flight requests are aborted", n, timeout)
+	}
+
+	s.Stop()
+}
+
+// Prepare creates all clients of the next run beforehand (warm pool): behavior scripts are compiled
+// and connections are established, so that on Start clients fire at once after their group delay,
+// without ramp-up, and without setup cost in the early metrics. Changing configuration discards prepared clients.
+func (s *Simulation) Prepare() error {
+	if s.running.Load() {
+		return fmt.Errorf("Simulation: Error: Cannot prepare clients while running")
+	}
+
+	s.DiscardPrepared()
+
+	err := s.prepareRun()
+	if err != nil {
+		return err
+	}
+
+	prepared := make(map[string][]*Client, len(s.clientsConfigs))
+	for _, config := range s.clientsConfigs {
+		clients := make([]*Client, config.Count)
+		for clientIndex := range clients {
+			clients[clientIndex] = s.newClient(config, clientIndex)
+			clients[clientIndex].establishConnection()
+		}
+		prepared[config.Id] = clients
+	}
+
+	s.mu.Lock()
+	s.prepared = prepared
+	s.mu.Unlock()
+
+	log.Printf("Simulation: Prepared clients of %d groups with seed %d\n", len(prepared), s.seed)
+
+	return nil
+}
+
+// IsPrepared returns whether clients of the next run are prepared
+func (s *Simulation) IsPrepared() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prepared != nil
+}
+
+// DiscardPrepared releases clients prepared for the next run, if any, and their script pool
+func (s *Simulation) DiscardPrepared() {
+	s.mu.Lock()
+	prepared := s.prepared
+	s.prepared = nil
+	scriptPool := s.scriptPool
+	s.mu.Unlock()
+
+	if prepared == nil {
+		return
+	}
+
+	for _, clients := range prepared {
+		for _, client := range clients {
+			client.GetBehavior().Close()
+		}
+	}
+	if scriptPool != nil {
+		scriptPool.Stop()
+	}
+	log.Println("Simulation: Discarded prepared clients")
+}
+
+// prepareRun resolves behavior scripts, picks the seed and creates the script pool for the next run
+func (s *Simulation) prepareRun() error {
+	err := s.resolveBehaviors()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seed = s.settings.Seed
+	if s.seed == 0 {
+		s.seed = time.Now().UnixNano()
+	}
+	s.scriptPool = nil
+	if s.settings.ScriptWorkers > 0 {
+		s.scriptPool = NewScriptPool(s.settings.ScriptWorkers)
+	}
+
+	return nil
+}
+
+// resolveBehaviors loads behavior scripts referenced by path and stores resolved sources in client configs
+func (s *Simulation) resolveBehaviors() error {
+	s.mu.Lock()
+	behaviorsDir := s.behaviorsDir
+	s.mu.Unlock()
+
+	for i, config := range s.clientsConfigs {
+		source, err := resolveBehavior(behaviorsDir, config.Behavior)
+		if err != nil {
+			return fmt.Errorf("Simulation: Error: Client group '%s': %v", config.Id, err)
+		}
+		s.clientsConfigs[i].BehaviorSource = source
+	}
+
+	return nil
+}
+
+// behaviorHash returns short hash of behavior script source, empty if there is no script
+func behaviorHash(source string) string {
+	if strings.TrimSpace(source) == "" {
+		return ""
+	}
+	h := fnv.New64a()
+	h.Write([]byte(source))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// resolveBehavior returns behavior script source, reading it from the behaviors directory if it is a file reference
+func resolveBehavior(behaviorsDir, behavior string) (string, error) {
+	ref, isFile := strings.CutPrefix(strings.TrimSpace(behavior), behaviorFilePrefix)
+	if !isFile {
+		return behavior, nil
+	}
+
+	if behaviorsDir == "" {
+		return "", fmt.Errorf("behavior file references are disabled, behaviors directory is not configured")
+	}
+
+	// Root restricts access to the behaviors directory, including via ".." and symlinks
+	root, err := os.OpenRoot(behaviorsDir)
+	if err != nil {
+		return "", fmt.Errorf("cannot open behaviors directory: %v", err)
+	}
+	defer root.Close()
+
+	source, err := root.ReadFile(strings.TrimLeft(ref, "/"))
+	if err != nil {
+		return "", fmt.Errorf("cannot read behavior file '%s': %v", ref, err)
+	}
+
+	return string(source), nil
+}
+
+// rampUpDuration returns time from start until the last client group is expected to be fully started,
+// prepared clients are started without ramp-up
+func (s *Simulation) rampUpDuration(prepared bool) time.Duration {
+	var longest time.Duration
+	for _, config := range s.clientsConfigs {
+		d := config.Delay
+		if config.RampUpMode == RampUpGradual && !prepared {
+			d += config.RampUpTime
+		}
+		if d > longest {
+			longest = d
+		}
+	}
+	return longest
+}
+
+// targetClients returns number of clients configured in all groups
+func (s *Simulation) targetClients() int64 {
+	var total int64
+	for _, config := range s.clientsConfigs {
+		total += int64(config.Count)
+	}
+	return total
+}
+
+// run creates and starts all clients based on configurations, or starts prepared clients if given
+func (s *Simulation) run(prepared map[string][]*Client) {
+	rng := rand.New(rand.NewSource(s.seed))
+	for _, config := range s.clientsConfigs {
+		if clients, ok := prepared[config.Id]; ok {
+			log.Printf("Simulation: Starting %d prepared clients simultaneously\n", len(clients))
+			s.wg.Go(func() { s.releaseClients(config, clients, time.Now().Add(config.Delay)) })
+			continue
+		}
+
+		if config.RampUpMode == RampUpHerd {
+			log.Printf("Simulation: Starting %d clients simultaneously\n", config.Count)
+			s.wg.Go(func() { s.startClientsHerd(config) })
+			continue
+		}
+
 		var delay time.Duration
-		if config.RampUpTime <= 0 {
+		if config.RampUpTime <= 0 || config.RampUpMode == RampUpImmediate {
 			delay = 0
 			log.Printf("Simulation: Starting %d clients (almost) immediately\n", config.Count)
 		} else {
@@ -261,42 +1068,149 @@ func (s *Simulation) run() {
 		}
 
 		for clientIndex := 0; clientIndex < config.Count; clientIndex++ {
+			jitterPercent := 0.5 // jitter = ±50% of delay
+			jitter := time.Duration(float64(delay) * jitterPercent * (rng.Float64()*2 - 1))
+			actualDelay := config.Delay + delay*time.Duration(clientIndex) + jitter
 			s.wg.Go(func() {
-				jitterPercent := 0.5 // jitter = ±50% of delay
-				jitter := time.Duration(float64(delay) * jitterPercent * (rand.Float64()*2 - 1))
-				actualDelay := config.Delay + delay*time.Duration(clientIndex) + jitter
 				s.startClientIn(
 					actualDelay,
-					config.Id,
-					groupIndex,
+					config,
 					clientIndex,
-					config.RequestRate,
-					config.Behavior,
 				)
 			})
 		}
 	}
 }
 
+// replayTimeline starts all clients at once, each sending its requests recorded in the timeline at their offsets,
+// ignoring ramp-up, request rate and jitter. Recorded requests of clients which do not exist in the current
+// configuration are skipped, and clients without recorded requests are not started
+func (s *Simulation) replayTimeline(prepared map[string][]*Client, replay *RequestTimeline, startTime time.Time) {
+	requests := replay.byClient()
+
+	var replayed []*Client
+	var replayedRequests int
+	for _, config := range s.clientsConfigs {
+		clients, ok := prepared[config.Id]
+		if !ok {
+			clients = make([]*Client, config.Count)
+			for clientIndex := range clients {
+				clients[clientIndex] = s.newClient(config, clientIndex)
+			}
+		}
+
+		for _, client := range clients {
+			if _, ok := requests[client.id]; !ok {
+				client.GetBehavior().Close()
+				continue
+			}
+			replayed = append(replayed, client)
+			replayedRequests += len(requests[client.id])
+		}
+	}
+
+	log.Printf("Simulation: Replaying %d of %d recorded requests by %d clients\n", replayedRequests, len(replay.Requests), len(replayed))
+
+	if s.ctx.Err() != nil {
+		for _, client := range replayed {
+			client.GetBehavior().Close()
+		}
+		return
+	}
+
+	s.startClients(replayed, func(client *Client) {
+		client.StartReplay(s.ctx, startTime, requests[client.id])
+	})
+}
+
+// clientSeed derives a deterministic seed for a client's random generator from run seed, client group id and client position
+func clientSeed(seed int64, groupId string, clientIndex int) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(groupId))
+
+	// splitmix64 finalizer, so that neighbouring clients get unrelated sequences
+	z := uint64(seed) + h.Sum64() + uint64(clientIndex) + 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return int64(z ^ (z >> 31))
+}
+
 // startClientIn starts single client with the given delay
-func (s *Simulation) startClientIn(delay time.Duration, groupId string, groupIndex, clientIndex int, requestRate time.Duration, behavior string) {
+func (s *Simulation) startClientIn(delay time.Duration, config ClientConfig, clientIndex int) {
 	err := SleepWithContext(s.ctx, delay)
 	if err != nil {
-		// log.Printf("Simulation: Warning: Failed to start client %d-%d, because simulation was cancelled", groupIndex, clientIndex)
+		// log.Printf("Simulation: Warning: Failed to start client %s-%d, because simulation was cancelled", config.Id, clientIndex)
 		return
 	}
 
+	client := s.newClient(config, clientIndex)
+
+	s.startClients([]*Client{client}, func(client *Client) {
+		client.Start(s.ctx, config.RequestRate)
+	})
+}
+
+// startClientsHerd prepares all clients of the group, and starts them at once after the group delay,
+// so that their first requests arrive simultaneously
+func (s *Simulation) startClientsHerd(config ClientConfig) {
+	releaseAt := time.Now().Add(config.Delay)
+
+	// Create clients beforehand, so that behavior scripts loading does not spread the start
+	clients := make([]*Client, config.Count)
+	for clientIndex := range clients {
+		clients[clientIndex] = s.newClient(config, clientIndex)
+	}
+
+	s.releaseClients(config, clients, releaseAt)
+}
+
+// releaseClients starts already created clients of the group at once at the given time
+func (s *Simulation) releaseClients(config ClientConfig, clients []*Client, releaseAt time.Time) {
+	err := SleepWithContext(s.ctx, time.Until(releaseAt))
+	if err != nil {
+		for _, client := range clients {
+			client.GetBehavior().Close()
+		}
+		return
+	}
+
+	s.startClients(clients, func(client *Client) {
+		client.Start(s.ctx, config.RequestRate)
+	})
+}
+
+// startClients adds clients to the run and starts them with the mutex held, so that Stop and StopDrain
+// see only started clients, clients are not started if the run is being drained
+func (s *Simulation) startClients(clients []*Client, start func(client *Client)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.draining.Load() {
+		for _, client := range clients {
+			client.GetBehavior().Close()
+		}
+		return
+	}
+
+	s.clients = append(s.clients, clients...)
+	for _, client := range clients {
+		start(client)
+	}
+}
+
+// newClient creates (but does not start) a client for the given group and position,
+// client id is derived from group id, so that it is stable across runs regardless of groups order
+func (s *Simulation) newClient(config ClientConfig, clientIndex int) *Client {
 	client := NewClient(
-		fmt.Sprintf("client-%d-%d", groupIndex, clientIndex),
-		groupId,
+		fmt.Sprintf("%s-%d", config.Id, clientIndex),
 		s.network,
 		s.metrics,
-		behavior,
+		config,
+		clientSeed(s.seed, config.Id, clientIndex),
+		s.settings.ScriptQueueSize,
+		s.scriptPool,
 	)
-
-	s.mu.Lock()
-	s.clients = append(s.clients, client)
-	s.mu.Unlock()
-
-	client.Start(s.ctx, requestRate)
+	client.timeline = s.timeline
+	client.paused = &s.paused
+	return client
 }
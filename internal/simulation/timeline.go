@@ -0,0 +1,96 @@
+package simulation
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"time"
+)
+
+// TimelineRequest is a request arrival captured from a run
+type TimelineRequest struct {
+	Offset    time.Duration // Time from simulation start to the arrival
+	ClientId  string
+	Group     string
+	TraceId   string
+	SizeBytes int    // Sampled payload size (0 if size of request data was used)
+	Kind      string // Sampled request kind (empty if client has no kinds)
+}
+
+// RequestTimeline is a sequence of request arrivals of a run, which can be replayed in another run,
+// so that changes of server and network behaviors are compared on exactly the same load
+type RequestTimeline struct {
+	Requests  []TimelineRequest
+	Truncated bool // Capacity was reached, later arrivals are not captured
+}
+
+// Timeline captures request arrivals of the current (or last) run, up to the capacity
+type Timeline struct {
+	startTime time.Time
+	capacity  int // Maximum number of captured arrivals (0 disables capture)
+	timeline  RequestTimeline
+	mu        sync.Mutex
+}
+
+// NewTimeline creates a new timeline, capture is disabled until started with positive capacity
+func NewTimeline() *Timeline {
+	return &Timeline{}
+}
+
+// Start discards previously captured arrivals and starts capturing arrivals of the run started at the given time
+func (t *Timeline) Start(startTime time.Time, capacity int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.startTime = startTime
+	t.capacity = capacity
+	t.timeline = RequestTimeline{}
+}
+
+// record captures request arrival at the current time
+func (t *Timeline) record(arrival TimelineRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.capacity <= 0 || t.timeline.Truncated {
+		return
+	}
+	if len(t.timeline.Requests) >= t.capacity {
+		t.timeline.Truncated = true
+		return
+	}
+
+	arrival.Offset = time.Since(t.startTime)
+	t.timeline.Requests = append(t.timeline.Requests, arrival)
+}
+
+// GetTimeline returns a copy of arrivals captured so far, ordered by offset
+func (t *Timeline) GetTimeline() RequestTimeline {
+	t.mu.Lock()
+	timeline := RequestTimeline{
+		Requests:  slices.Clone(t.timeline.Requests),
+		Truncated: t.timeline.Truncated,
+	}
+	t.mu.Unlock()
+
+	sortTimelineRequests(timeline.Requests)
+	return timeline
+}
+
+// byClient splits timeline into arrivals of each client, ordered by offset
+func (rt *RequestTimeline) byClient() map[string][]TimelineRequest {
+	requests := make(map[string][]TimelineRequest)
+	for _, r := range rt.Requests {
+		requests[r.ClientId] = append(requests[r.ClientId], r)
+	}
+	for _, clientRequests := range requests {
+		sortTimelineRequests(clientRequests)
+	}
+	return requests
+}
+
+// sortTimelineRequests orders arrivals by offset, concurrent clients could record them slightly out of order
+func sortTimelineRequests(requests []TimelineRequest) {
+	slices.SortStableFunc(requests, func(a, b TimelineRequest) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+}
@@ -0,0 +1,108 @@
+package simulation
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxTimeSeriesPoints bounds the number of per-second rows kept for a run (one hour)
+const maxTimeSeriesPoints = 3600
+
+// TimeSeriesPoint is a summary of one second of a simulation run
+type TimeSeriesPoint struct {
+	Timestamp         time.Time
+	Elapsed           time.Duration // Time from simulation start to the end of this second
+	RPS               float64       // Requests sent by clients per second
+	ErrorRate         float64       // Share of completed requests that failed (error response or network failure)
+	P50ResponseTime   time.Duration
+	P95ResponseTime   time.Duration
+	P99ResponseTime   time.Duration
+	CPUUtilization    float64
+	MemoryUtilization float64
+}
+
+// TimeSeries rolls up metrics into one row per second for the whole run duration
+type TimeSeries struct {
+	metrics *Metrics
+	points  []TimeSeriesPoint
+	mu      sync.RWMutex
+}
+
+// NewTimeSeries creates a new per-second aggregator over the given metrics
+func NewTimeSeries(metrics *Metrics) *TimeSeries {
+	return &TimeSeries{
+		metrics: metrics,
+		points:  make([]TimeSeriesPoint, 0, 60),
+	}
+}
+
+// GetPoints returns a copy of the rows collected so far
+func (ts *TimeSeries) GetPoints() []TimeSeriesPoint {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return slices.Clone(ts.points)
+}
+
+// Run collects one row per second until the simulation context is cancelled, previously collected rows are discarded
+func (ts *TimeSeries) Run(ctx context.Context, startTime time.Time) {
+	ts.mu.Lock()
+	ts.points = ts.points[:0]
+	ts.mu.Unlock()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	lastTick := startTime
+	lastSent := ts.metrics.ClientSentRequests.Load()
+	lastSucceeded, lastFailed := ts.completedRequests()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			sent := ts.metrics.ClientSentRequests.Load()
+			succeeded, failed := ts.completedRequests()
+
+			point := TimeSeriesPoint{
+				Timestamp: now,
+				Elapsed:   now.Sub(startTime),
+			}
+
+			if seconds := now.Sub(lastTick).Seconds(); seconds > 0 {
+				point.RPS = float64(sent-lastSent) / seconds
+			}
+			if completed := (succeeded - lastSucceeded) + (failed - lastFailed); completed > 0 {
+				point.ErrorRate = float64(failed-lastFailed) / float64(completed)
+			}
+
+			percentiles := ts.metrics.responseTimePercentiles(now, 0.5, 0.95, 0.99)
+			point.P50ResponseTime = percentiles[0]
+			point.P95ResponseTime = percentiles[1]
+			point.P99ResponseTime = percentiles[2]
+
+			ts.metrics.resourceStateMu.RLock()
+			point.CPUUtilization = ts.metrics.latestResourceState.CPUUtilization
+			point.MemoryUtilization = ts.metrics.latestResourceState.MemoryUtilization
+			ts.metrics.resourceStateMu.RUnlock()
+
+			ts.mu.Lock()
+			ts.points = append(ts.points, point)
+			if len(ts.points) > maxTimeSeriesPoints {
+				ts.points = ts.points[len(ts.points)-maxTimeSeriesPoints:]
+			}
+			ts.mu.Unlock()
+
+			lastTick, lastSent, lastSucceeded, lastFailed = now, sent, succeeded, failed
+		}
+	}
+}
+
+// completedRequests returns total numbers of succeeded and failed requests, as seen by clients
+func (ts *TimeSeries) completedRequests() (succeeded, failed int64) {
+	succeeded = ts.metrics.ClientSuccessResponses.Load()
+	failed = ts.metrics.ClientErrorResponses.Load() + ts.metrics.ClientTimeoutRequests.Load() + ts.metrics.NetworkFailedRequests.Load()
+	return succeeded, failed
+}
@@ -0,0 +1,147 @@
+package simulation
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// transitionStep is how often intermediate behavior is applied during behavior transition
+const transitionStep = 100 * time.Millisecond
+
+// transitionSamples is the number of points used to represent intermediate curves during behavior transition
+const transitionSamples = 21
+
+// TransitionBehavior moves server behavior to the target gradually over the given duration, behavior time keeps running.
+// Target is applied at once if server is not running or duration is not positive.
+func (s *Server) TransitionBehavior(target ServerBehavior, duration time.Duration) {
+	s.mu.Lock()
+	s.cancelTransition()
+	if !s.running.Load() || duration <= 0 {
+		s.mu.Unlock()
+		s.SetBehavior(target)
+		return
+	}
+
+	from := s.behavior
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.transitionCancel = cancel
+	s.mu.Unlock()
+
+	go s.runTransition(ctx, from, target, duration)
+}
+
+// cancelTransition stops ongoing behavior transition, must be called with mutex held
+func (s *Server) cancelTransition() {
+	if s.transitionCancel != nil {
+		s.transitionCancel()
+		s.transitionCancel = nil
+	}
+}
+
+// runTransition periodically applies behavior blended between from and to, until transition is complete or cancelled
+func (s *Server) runTransition(ctx context.Context, from, to ServerBehavior, duration time.Duration) {
+	ticker := time.NewTicker(transitionStep)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			progress := min(float64(time.Since(start))/float64(duration), 1)
+
+			behavior := to
+			if progress < 1 {
+				behavior = BlendServerBehavior(from, to, progress)
+			}
+
+			if !s.applyTransitionStep(ctx, behavior) || progress >= 1 {
+				return
+			}
+		}
+	}
+}
+
+// applyTransitionStep sets intermediate behavior, preserving behavior start time and server state,
+// returns false if transition was cancelled meanwhile
+func (s *Server) applyTransitionStep(ctx context.Context, behavior ServerBehavior) bool {
+	s.mu.Lock()
+	s.resourceStateMu.Lock()
+	defer s.mu.Unlock()
+	defer s.resourceStateMu.Unlock()
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	scriptChanged := behavior.Script != s.behavior.Script
+	s.behavior = behavior
+	s.resourceSettings = behavior.ResourceSettings
+	s.setupCurveFunctions()
+	if scriptChanged {
+		s.restartScript()
+	}
+
+	return true
+}
+
+// BlendServerBehavior returns behavior in between from and to, where progress 0 is from and 1 is to.
+// Curves are blended in absolute units at the same relative position of the time axis, and resampled.
+// Settings which can not be blended are taken from the target behavior.
+func BlendServerBehavior(from, to ServerBehavior, progress float64) ServerBehavior {
+	b := to
+	b.To = int(math.Round(lerp(float64(from.To), float64(to.To), progress)))
+	b.ResponseTimeFrom = int(math.Round(lerp(float64(from.ResponseTimeFrom), float64(to.ResponseTimeFrom), progress)))
+	b.ResponseTimeTo = int(math.Round(lerp(float64(from.ResponseTimeTo), float64(to.ResponseTimeTo), progress)))
+	b.CapacityRPS = lerp(from.CapacityRPS, to.CapacityRPS, progress)
+
+	b.Errors = blendCurve(
+		from.Errors, 0, 1,
+		to.Errors, 0, 1,
+		0, 1,
+		progress,
+	)
+	b.ResponseTimeMin = blendCurve(
+		from.ResponseTimeMin, float64(from.ResponseTimeFrom), float64(from.ResponseTimeTo),
+		to.ResponseTimeMin, float64(to.ResponseTimeFrom), float64(to.ResponseTimeTo),
+		float64(b.ResponseTimeFrom), float64(b.ResponseTimeTo),
+		progress,
+	)
+	b.ResponseTimeMax = blendCurve(
+		from.ResponseTimeMax, float64(from.ResponseTimeFrom), float64(from.ResponseTimeTo),
+		to.ResponseTimeMax, float64(to.ResponseTimeFrom), float64(to.ResponseTimeTo),
+		float64(b.ResponseTimeFrom), float64(b.ResponseTimeTo),
+		progress,
+	)
+
+	return b
+}
+
+// blendCurve samples both curves in their own Y ranges, blends values, and normalizes them into [minY,maxY]
+func blendCurve(fromPoints []BehaviorPoint, fromMinY, fromMaxY float64, toPoints []BehaviorPoint, toMinY, toMaxY float64, minY, maxY float64, progress float64) []BehaviorPoint {
+	getFrom := CurveFunction(0, 1, fromMinY, fromMaxY, fromPoints)
+	getTo := CurveFunction(0, 1, toMinY, toMaxY, toPoints)
+
+	points := make([]BehaviorPoint, transitionSamples)
+	for i := range points {
+		x := float64(i) / float64(transitionSamples-1)
+		y := lerp(getFrom(x), getTo(x), progress)
+
+		var ny float64
+		if maxY != minY {
+			ny = min(max((y-minY)/(maxY-minY), 0), 1)
+		}
+
+		// Break points make curve linear between samples
+		points[i] = BehaviorPoint{X: x, Y: ny, Type: Break}
+	}
+
+	return points
+}
+
+// lerp linearly interpolates between a and b
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
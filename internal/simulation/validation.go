@@ -0,0 +1,362 @@
+package simulation
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationSeverity defines whether validation issue prevents simulation from working as configured
+type ValidationSeverity int
+
+const (
+	ValidationWarning ValidationSeverity = iota // Configuration works, but likely not as intended
+	ValidationError                             // Configuration is invalid
+)
+
+func (vs ValidationSeverity) String() string {
+	switch vs {
+	case ValidationWarning:
+		return "warning"
+	case ValidationError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ValidationIssue describes a single problem with simulation configuration
+type ValidationIssue struct {
+	Severity ValidationSeverity
+	Path     string // Path to the field in API terms, like "clients[group-1].requestRate" or "server.rtmin[1].x"
+	Message  string
+}
+
+// validator collects validation issues
+type validator struct {
+	issues []ValidationIssue
+}
+
+func (v *validator) errorf(path, format string, args ...any) {
+	v.issues = append(v.issues, ValidationIssue{Severity: ValidationError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...any) {
+	v.issues = append(v.issues, ValidationIssue{Severity: ValidationWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// fraction checks that value is a probability (or share) in [0,1]
+func (v *validator) fraction(path string, value float64) {
+	if value < 0 || value > 1 {
+		v.errorf(path, "must be between 0 and 1, got %v", value)
+	}
+}
+
+// nonNegative checks that value is not negative
+func (v *validator) nonNegative(path string, value float64) {
+	if value < 0 {
+		v.errorf(path, "must not be negative, got %v", value)
+	}
+}
+
+// curve checks behavior curve points are within [0,1] and ordered by x
+func (v *validator) curve(path string, points []BehaviorPoint) {
+	if len(points) < 2 {
+		v.errorf(path, "must have at least 2 points, got %d", len(points))
+		return
+	}
+	for i, p := range points {
+		v.fraction(fmt.Sprintf("%s[%d].x", path, i), p.X)
+		v.fraction(fmt.Sprintf("%s[%d].y", path, i), p.Y)
+		if i > 0 && p.X < points[i-1].X {
+			v.errorf(fmt.Sprintf("%s[%d].x", path, i), "points must be ordered by x, %v goes after %v", p.X, points[i-1].X)
+		}
+	}
+}
+
+// Validate checks the whole current configuration (settings, client groups and their behavior scripts,
+// server and network behaviors) and returns all found issues, empty if configuration is valid
+func (s *Simulation) Validate() []ValidationIssue {
+	v := &validator{}
+
+	s.mu.Lock()
+	settings := s.settings
+	behaviorsDir := s.behaviorsDir
+	s.mu.Unlock()
+
+	validateSettings(v, settings)
+	s.validateClientConfigs(v, behaviorsDir)
+	validateServerBehavior(v, s.GetServerBehavior(), behaviorsDir)
+	validateNetworkBehavior(v, s.GetNetworkBehavior())
+	s.validateSingleFlight(v)
+
+	return v.issues
+}
+
+// validateSingleFlight warns if single-flight server has no requests to coalesce,
+// requests get a key only from client behavior scripts
+func (s *Simulation) validateSingleFlight(v *validator) {
+	if !s.GetServerBehavior().SingleFlight {
+		return
+	}
+	for _, config := range s.GetClientConfigs() {
+		if strings.TrimSpace(config.Behavior) != "" {
+			return
+		}
+	}
+	v.warnf("server.singleFlight", "has no effect, requests are coalesced by req[\"key\"] which only client behavior scripts set")
+}
+
+// validateSettings checks simulation settings
+func validateSettings(v *validator, settings Settings) {
+	if settings.TimeScale <= 0 {
+		v.errorf("settings.timeScale", "must be positive, got %v", settings.TimeScale)
+	}
+	if settings.ScriptQueueSize <= 0 {
+		v.errorf("settings.scriptQueueSize", "must be positive, got %d", settings.ScriptQueueSize)
+	}
+	v.nonNegative("settings.scriptWorkers", float64(settings.ScriptWorkers))
+	v.fraction("settings.stabilizationErrorRate", settings.StabilizationErrorRate)
+	v.nonNegative("settings.starvationThresholdSec", settings.StarvationThresholdSec)
+	v.nonNegative("settings.goodputDeadlineMs", float64(settings.GoodputDeadlineMs))
+	v.nonNegative("settings.timelineCapacity", float64(settings.TimelineCapacity))
+	v.nonNegative("settings.metricsWindowMs", float64(settings.MetricsWindowMs))
+	v.nonNegative("settings.sloLatencyMs", float64(settings.SLOLatencyMs))
+	v.nonNegative("settings.apdexThresholdMs", float64(settings.ApdexThresholdMs))
+}
+
+// validateClientConfigs checks client groups, and compiles their behavior scripts
+func (s *Simulation) validateClientConfigs(v *validator, behaviorsDir string) {
+	configs := s.GetClientConfigs()
+	if len(configs) == 0 {
+		v.errorf("clients", "no client configurations")
+		return
+	}
+
+	for _, config := range configs {
+		path := fmt.Sprintf("clients[%s]", config.Id)
+
+		if config.Count <= 0 {
+			v.errorf(path+".count", "must be positive, got %d", config.Count)
+		}
+		if config.RequestRate <= 0 && config.WorkloadModel != WorkloadClosed {
+			v.errorf(path+".requestRate", "must be positive, got %v", config.RequestRate)
+		}
+		v.nonNegative(path+".thinkTimeMs", config.ThinkTime.Seconds())
+		if config.WorkloadModel != WorkloadClosed && config.ThinkTime > 0 {
+			v.warnf(path+".thinkTimeMs", "is ignored with %s workload model", config.WorkloadModel)
+		}
+		if config.WorkloadModel == WorkloadClosed && config.ThinkTime < minThinkTime {
+			v.warnf(path+".thinkTimeMs", "is raised to %v with closed workload model, so that clients do not spin on requests which complete instantly", minThinkTime)
+		}
+		v.nonNegative(path+".rampUpTime", config.RampUpTime.Seconds())
+		v.nonNegative(path+".startupDelay", config.Delay.Seconds())
+		if config.RampUpMode != RampUpGradual && config.RampUpTime > 0 {
+			v.warnf(path+".rampUpTime", "is ignored with %s ramp-up mode", config.RampUpMode)
+		}
+		v.fraction(path+".firstRequestFailureRate", config.FirstRequestFailureRate)
+		v.nonNegative(path+".retryDelay", config.RetryDelay.Seconds())
+		v.nonNegative(path+".maxRetryDelay", config.MaxRetryDelay.Seconds())
+		if config.JitterMode != RetryJitterNone && config.RetryDelay <= 0 {
+			v.warnf(path+".retryJitterMode", "has no effect without retryDelay")
+		}
+		v.nonNegative(path+".errorCooldownMs", config.ErrorCooldown.Seconds())
+		v.nonNegative(path+".timeoutMs", config.Timeout.Seconds())
+		if config.TimeoutJitterPct < 0 || config.TimeoutJitterPct > 100 {
+			v.errorf(path+".timeoutJitterPct", "must be between 0 and 100, got %v", config.TimeoutJitterPct)
+		}
+		if config.Timeout <= 0 && config.TimeoutJitterPct > 0 {
+			v.warnf(path+".timeoutJitterPct", "has no effect without timeoutMs")
+		}
+		v.nonNegative(path+".abandonAfterFailures", float64(config.AbandonAfterFailures))
+		v.nonNegative(path+".batchSize", float64(config.BatchSize))
+		var payloadWeight float64
+		for i, ps := range config.PayloadSizes {
+			v.nonNegative(fmt.Sprintf("%s.payloadSizes[%d].sizeBytes", path, i), float64(ps.SizeBytes))
+			v.nonNegative(fmt.Sprintf("%s.payloadSizes[%d].weight", path, i), ps.Weight)
+			payloadWeight += max(ps.Weight, 0)
+		}
+		if len(config.PayloadSizes) > 0 && payloadWeight <= 0 {
+			v.warnf(path+".payloadSizes", "all weights are zero, size of request data is used")
+		}
+		var kindWeight float64
+		kindNames := make(map[string]bool)
+		for i, rk := range config.RequestKinds {
+			kindPath := fmt.Sprintf("%s.requestKinds[%d]", path, i)
+			if rk.Name == "" {
+				v.errorf(kindPath+".name", "must not be empty")
+			} else if kindNames[rk.Name] {
+				v.errorf(kindPath+".name", "duplicate kind '%s'", rk.Name)
+			}
+			kindNames[rk.Name] = true
+			v.nonNegative(kindPath+".weight", rk.Weight)
+			v.nonNegative(kindPath+".cpuCost", rk.CPUCost)
+			kindWeight += max(rk.Weight, 0)
+		}
+		if len(config.RequestKinds) > 0 && kindWeight <= 0 {
+			v.warnf(path+".requestKinds", "all weights are zero, requests are sent without kind")
+		}
+		if ar := config.AdaptiveRate; ar.Enabled {
+			v.nonNegative(path+".adaptiveRate.targetLatencyMs", ar.TargetLatency.Seconds())
+			v.fraction(path+".adaptiveRate.decreaseFactor", ar.DecreaseFactor)
+			if ar.MaxRPS > 0 && ar.MaxRPS < ar.MinRPS {
+				v.errorf(path+".adaptiveRate.maxRps", "must not be less than minRps (%v), got %v", ar.MinRPS, ar.MaxRPS)
+			}
+		}
+
+		source, err := resolveBehavior(behaviorsDir, config.Behavior)
+		if err != nil {
+			v.errorf(path+".behavior", "%v", err)
+			continue
+		}
+		if strings.TrimSpace(source) == "" {
+			continue
+		}
+		behavior, err := NewStarlarkClientBehavior(source, 1, nil, "", "")
+		if err != nil {
+			v.errorf(path+".behavior", "%v", err)
+			continue
+		}
+		behavior.Close()
+	}
+}
+
+// validateServerBehavior checks server behavior curves, resource settings and other server options
+func validateServerBehavior(v *validator, behavior ServerBehavior, behaviorsDir string) {
+	v.nonNegative("server.to", float64(behavior.To))
+	v.nonNegative("server.rtfrom", float64(behavior.ResponseTimeFrom))
+	if behavior.ResponseTimeTo < behavior.ResponseTimeFrom {
+		v.errorf("server.rtto", "must not be less than rtfrom (%d), got %d", behavior.ResponseTimeFrom, behavior.ResponseTimeTo)
+	}
+	v.curve("server.errors", behavior.Errors)
+	v.curve("server.rtmin", behavior.ResponseTimeMin)
+	v.curve("server.rtmax", behavior.ResponseTimeMax)
+	validateLatencySpikes(v, "server.latencySpikes", behavior.LatencySpikes)
+	for i, spike := range behavior.LatencySpikes {
+		if spike.Dimension != SpikeAll {
+			v.warnf(fmt.Sprintf("server.latencySpikes[%d].dimension", i), "is ignored, server spikes add to processing time")
+		}
+	}
+
+	v.nonNegative("server.capacityRps", behavior.CapacityRPS)
+	v.nonNegative("server.idempotencyWindowMs", float64(behavior.IdempotencyWindowMs))
+	v.nonNegative("server.capacityWarmupSec", behavior.CapacityWarmupSec)
+	v.fraction("server.capacityWarmupFraction", behavior.CapacityWarmupFraction)
+	v.nonNegative("server.responseSizeBytes", float64(behavior.ResponseSizeBytes))
+
+	for group, override := range behavior.GroupOverrides {
+		path := fmt.Sprintf("server.groupOverrides[%s]", group)
+		v.nonNegative(path+".responseTimeMultiplier", override.ResponseTimeMultiplier)
+		v.nonNegative(path+".errorRateMultiplier", override.ErrorRateMultiplier)
+	}
+
+	v.nonNegative("server.fanOutBackends", float64(behavior.FanOutBackends))
+	v.fraction("server.fanOutBackendErrorRate", behavior.FanOutBackendErrorRate)
+	v.nonNegative("server.fanOutQuorum", float64(behavior.FanOutQuorum))
+	if behavior.FanOutQuorum > behavior.FanOutBackends {
+		v.warnf("server.fanOutQuorum", "is more than fanOutBackends (%d), all backends are required", behavior.FanOutBackends)
+	}
+	v.fraction("server.latencyCorrelation", behavior.LatencyCorrelation)
+	v.fraction("server.serialFraction", behavior.SerialFraction)
+	if source, err := resolveBehavior(behaviorsDir, behavior.Script); err != nil {
+		v.errorf("server.script", "%v", err)
+	} else if strings.TrimSpace(source) != "" {
+		script, err := NewStarlarkServerBehavior(source)
+		if err != nil {
+			v.errorf("server.script", "%v", err)
+		} else {
+			if !script.HasHandleHook() {
+				v.warnf("server.script", "does not define on_handle hook, has no effect")
+			}
+			script.Close()
+		}
+	}
+
+	if !behavior.EnableResourceManagement {
+		if behavior.DeprioritizeRetries {
+			v.warnf("server.deprioritizeRetries", "has no effect without resource management")
+		}
+		return
+	}
+
+	rs := behavior.ResourceSettings
+	if rs.MaxConcurrentRequests <= 0 {
+		v.errorf("server.resources.maxConcurrentRequests", "must be positive, got %d", rs.MaxConcurrentRequests)
+	}
+	if rs.MaxMemoryMB <= 0 {
+		v.errorf("server.resources.maxMemoryMB", "must be positive, got %d", rs.MaxMemoryMB)
+	}
+	if rs.MaxQueueSize <= 0 {
+		v.errorf("server.resources.maxQueueSize", "must be positive, got %d", rs.MaxQueueSize)
+	}
+	v.nonNegative("server.resources.memoryLeakRateMBPerSec", rs.MemoryLeakRateMBPerSec)
+	v.nonNegative("server.resources.memoryPerRequestMB", rs.MemoryPerRequestMB)
+	v.nonNegative("server.resources.memoryPerResponseKB", rs.MemoryPerResponseKB)
+	v.nonNegative("server.resources.gcPauseIntervalSec", rs.GCPauseIntervalSec)
+	v.nonNegative("server.resources.gcPauseDurationMs", rs.GCPauseDurationMs)
+	v.nonNegative("server.resources.queueDepthLatencyFactor", rs.QueueDepthLatencyFactor)
+	v.fraction("server.resources.ioBoundFraction", rs.IOBoundFraction)
+	v.nonNegative("server.resources.maxConcurrentIO", float64(rs.MaxConcurrentIO))
+	if rs.IOBoundFraction > 0 && rs.MaxConcurrentIO <= 0 {
+		v.warnf("server.resources.maxConcurrentIO", "is not set, IO-bound fraction has no effect")
+	}
+	v.nonNegative("server.resources.maxDispatchQueueSize", float64(rs.MaxDispatchQueueSize))
+	v.fraction("server.resources.swapThreshold", rs.SwapThreshold)
+	v.nonNegative("server.resources.swapLatencyMultiplier", rs.SwapLatencyMultiplier)
+	if rs.SwapThreshold > 0 && rs.SwapLatencyMultiplier <= 0 {
+		v.warnf("server.resources.swapLatencyMultiplier", "is not set, swap threshold has no effect")
+	}
+	v.nonNegative("server.resources.cpuImpactWeight", rs.CPUImpactWeight)
+	v.nonNegative("server.resources.memoryImpactWeight", rs.MemoryImpactWeight)
+	v.nonNegative("server.resources.threadsImpactWeight", rs.ThreadsImpactWeight)
+	v.nonNegative("server.resources.maxResourceImpactMultiplier", rs.MaxResourceImpactMultiplier)
+	if rs.MaxResourceImpactMultiplier > 0 && rs.MaxResourceImpactMultiplier < 1 {
+		v.warnf("server.resources.maxResourceImpactMultiplier", "is below 1, resource pressure makes requests faster")
+	}
+}
+
+// validateLatencySpikes checks scheduled latency spikes
+func validateLatencySpikes(v *validator, path string, spikes []LatencySpike) {
+	for i, spike := range spikes {
+		spikePath := fmt.Sprintf("%s[%d]", path, i)
+		v.nonNegative(spikePath+".atSec", spike.AtSec)
+		v.nonNegative(spikePath+".extraLatencyMs", spike.ExtraLatencyMs)
+		if spike.DurationSec <= 0 {
+			v.errorf(spikePath+".durationSec", "must be positive, got %v", spike.DurationSec)
+		}
+	}
+}
+
+// validateNetworkBehavior checks network behavior curves and bandwidth
+func validateNetworkBehavior(v *validator, behavior NetworkBehavior) {
+	v.nonNegative("network.to", float64(behavior.To))
+	v.nonNegative("network.latfrom", float64(behavior.LatencyFrom))
+	if behavior.LatencyTo < behavior.LatencyFrom {
+		v.errorf("network.latto", "must not be less than latfrom (%d), got %d", behavior.LatencyFrom, behavior.LatencyTo)
+	}
+	v.curve("network.drops", behavior.DropRate)
+	v.curve("network.latmin", behavior.LatencyMin)
+	v.curve("network.latmax", behavior.LatencyMax)
+	if len(behavior.ResponseLatencyMin) > 0 {
+		v.curve("network.resplatmin", behavior.ResponseLatencyMin)
+	}
+	if len(behavior.ResponseLatencyMax) > 0 {
+		v.curve("network.resplatmax", behavior.ResponseLatencyMax)
+	}
+	v.nonNegative("network.bandwidthBytesPerSec", float64(behavior.BandwidthBytesPerSec))
+	v.fraction("network.blackHoleRate", behavior.BlackHoleRate)
+	v.nonNegative("network.connectionSetupMs", float64(behavior.ConnectionSetupMs))
+	v.nonNegative("network.tlsHandshakeMs", float64(behavior.TLSHandshakeMs))
+	v.nonNegative("network.connectionIdleTimeoutMs", float64(behavior.ConnectionIdleTimeoutMs))
+	if behavior.LatencyCorrelation < 0 || behavior.LatencyCorrelation >= 1 {
+		v.errorf("network.latencyCorrelation", "must be at least 0 and less than 1, got %v", behavior.LatencyCorrelation)
+	}
+	validateLatencySpikes(v, "network.latencySpikes", behavior.LatencySpikes)
+	v.fraction("network.reorderRate", behavior.ReorderRate)
+	v.nonNegative("network.reorderMaxDelayMs", float64(behavior.ReorderMaxDelayMs))
+	if behavior.ReorderRate > 0 && behavior.ReorderMaxDelayMs <= 0 {
+		v.warnf("network.reorderMaxDelayMs", "is not set, reorder rate has no effect")
+	}
+	if behavior.ReorderRate > 0 && behavior.ConnectionSetupMs+behavior.TLSHandshakeMs <= 0 {
+		v.warnf("network.reorderRate", "has no effect without connection setup delay, connections are not reused")
+	}
+}
@@ -1,10 +1,12 @@
 package web
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
-	"math/rand"
+	"maps"
+	"net"
 	"net/http"
 	"sync"
 	"sync/atomic"
@@ -24,6 +26,87 @@ type Dashboard struct {
 	runIndex   atomic.Int64
 	mu         sync.RWMutex
 	stopTimer  *time.Timer // Timer for simulation time limit
+
+	metricsBaselines map[string]metricsBaseline // Named metrics counters snapshots to diff against
+
+	behaviorsDir string // Base directory for behavior script file references
+
+	maxDuration time.Duration // Hard ceiling on simulation run duration (0 disables)
+
+	history *metricsHistory // Metrics snapshots broadcast during the latest run
+
+	addr     string // Address the web server listens on
+	basePath string // Path prefix of all routes, empty serves them at the root
+}
+
+// DefaultAddr is the default address the dashboard web server listens on
+const DefaultAddr = ":8080"
+
+// metricsBaseline is a named snapshot of metrics counters
+type metricsBaseline struct {
+	counters map[string]int64
+	markedAt time.Time
+}
+
+// metricsHistoryCapacity is the number of metrics snapshots retained for a run, 10 minutes of 200ms emitter ticks
+const metricsHistoryCapacity = 3000
+
+// metricsHistory retains metrics snapshots broadcast during the latest run, oldest ones are dropped when it is full
+type metricsHistory struct {
+	runId     string
+	startedAt int64 // Unix time (ms) when the run was started
+	units     map[string]string
+	snapshots []map[string]any
+	dropped   int // Number of oldest snapshots dropped because of capacity
+	mu        sync.Mutex
+}
+
+// reset discards retained snapshots and starts retaining snapshots of the given run
+func (h *metricsHistory) reset(runId string, startedAt int64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.runId = runId
+	h.startedAt = startedAt
+	h.units = nil
+	h.snapshots = nil
+	h.dropped = 0
+}
+
+// append retains a snapshot, without units which are kept once per run,
+// late snapshots of previous runs are ignored
+func (h *metricsHistory) append(snapshot map[string]any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.runId == "" {
+		return
+	}
+	if timestamp, ok := snapshot["timestamp"].(int64); ok && timestamp < h.startedAt {
+		return
+	}
+
+	snapshot = maps.Clone(snapshot)
+	if units, ok := snapshot["units"].(map[string]string); ok {
+		h.units = units
+		delete(snapshot, "units")
+	}
+
+	h.snapshots = append(h.snapshots, snapshot)
+	if len(h.snapshots) > metricsHistoryCapacity {
+		h.dropped += len(h.snapshots) - metricsHistoryCapacity
+		h.snapshots = h.snapshots[len(h.snapshots)-metricsHistoryCapacity:]
+	}
+}
+
+// latest returns the last retained snapshot of the given run, nil if none was broadcast during the run yet
+func (h *metricsHistory) latest(runId string) map[string]any {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.runId != runId || len(h.snapshots) == 0 {
+		return nil
+	}
+	return h.snapshots[len(h.snapshots)-1]
 }
 
 // NewDashboard creates a new instance of Dashboard
@@ -33,10 +116,15 @@ func NewDashboard() *Dashboard {
 		mux:       http.NewServeMux(),
 		metricsWs: NewWebSocketHub(),
 		notifyWs:  NewWebSocketHub(),
-	}
 
-	log.Println("Dashboard: Setup routes")
-	SetupRoutes(d.mux, d)
+		metricsBaselines: make(map[string]metricsBaseline),
+
+		maxDuration: simulation.DefaultMaxDuration,
+
+		history: &metricsHistory{},
+
+		addr: DefaultAddr,
+	}
 
 	log.Println("Dashboard: Starting metrics forwarding goroutine")
 	go d.startMetricsForwarding()
@@ -44,10 +132,62 @@ func NewDashboard() *Dashboard {
 	return d
 }
 
-// ListenAndServe starts the dashboard web server
+// SetAddr sets the address the web server listens on, like ":8080" or "127.0.0.1:9000", must be called before ListenAndServe
+func (d *Dashboard) SetAddr(addr string) {
+	d.addr = addr
+}
+
+// SetBasePath sets path prefix of all routes, like "/simulator" behind a reverse proxy, must be called before ListenAndServe
+func (d *Dashboard) SetBasePath(basePath string) {
+	d.basePath = normalizeBasePath(basePath)
+}
+
+// ListenAndServe sets up routes and starts the dashboard web server
 func (d *Dashboard) ListenAndServe() {
-	log.Println("Dashboard: Available at http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", d.mux))
+	log.Println("Dashboard: Setup routes")
+	SetupRoutes(d.mux, d, d.basePath)
+
+	log.Printf("Dashboard: Available at http://%s%s", displayAddr(d.addr), d.basePath)
+	log.Fatal(http.ListenAndServe(d.addr, d.mux))
+}
+
+// displayAddr returns listen address as it can be opened in browser, with localhost if host is omitted
+func displayAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("localhost", port)
+}
+
+// SetBehaviorsDir sets the base directory for behavior script file references
+func (d *Dashboard) SetBehaviorsDir(dir string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.behaviorsDir = dir
+	if d.simulation != nil {
+		d.simulation.SetBehaviorsDir(dir)
+	}
+}
+
+// SetMaxDuration sets the hard ceiling on simulation run duration (0 disables it),
+// enforced by the simulation itself regardless of the time limit given on start
+func (d *Dashboard) SetMaxDuration(limit time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.maxDuration = limit
+	if d.simulation != nil {
+		d.simulation.SetMaxDuration(limit, d.maxDurationReached)
+	}
+}
+
+// maxDurationReached notifies clients that simulation was stopped by max duration
+func (d *Dashboard) maxDurationReached(limit time.Duration) {
+	log.Printf("Dashboard: Simulation max duration (%v) reached, simulation stopped", limit)
+	d.Notify("simulation_max_duration_reached", map[string]any{"maxDurationSec": limit.Seconds()})
+	d.Notify("simulation_stopped", nil)
 }
 
 // Notify sends a notification message to all connected notifyWs clients
@@ -65,25 +205,37 @@ func (d *Dashboard) Notify(eventType string, payload any) {
 	d.notifyWs.Broadcast(data)
 }
 
-// resetSimulationUnsafe resets the simulation without locking the mutex
-func (d *Dashboard) resetSimulationUnsafe() {
+// resetSimulationUnsafe resets the simulation without locking the mutex.
+// Previous simulation is stopped first (Stop waits for it up to a bounded time), new simulation is created anyway,
+// and error is returned if previous simulation did not stop cleanly and some of its goroutines are abandoned.
+func (d *Dashboard) resetSimulationUnsafe() error {
+	var err error
 	if d.simulation != nil {
 		log.Println("Dashboard: Stopping previous simulation")
 		d.simulation.Stop()
+		d.simulation.DiscardPrepared()
+		if d.simulation.IsAbandoned() {
+			err = fmt.Errorf("Previous simulation did not stop cleanly, some of its goroutines are abandoned")
+		}
 	}
 
 	log.Println("Dashboard: Added default client configuration: 100 clients with 3s ramp-up time and 0s delay")
 	d.simulation = simulation.NewSimulation(d.runIndex.Add(1))
+	d.simulation.SetBehaviorsDir(d.behaviorsDir)
+	d.simulation.SetMaxDuration(d.maxDuration, d.maxDurationReached)
+	clear(d.metricsBaselines) // Counters start over with new simulation
+
+	// 100 clients, 100ms request rate, 3 seconds ramp-up time, 0 delay
+	// Empty id gets stable "group-1" id, so that default group is the same across simulations
+	d.simulation.AddClientsConfig(simulation.ClientConfig{
+		Count:       100,
+		RequestRate: 100 * time.Millisecond,
+		RampUpTime:  3 * time.Second,
+		Delay:       0,
+		Behavior:    "",
+	})
 
-	id := fmt.Sprintf("%08x", rand.Uint32()) // random hex (8 characters)
-	d.simulation.AddClientsConfig(           // 100 clients, 100ms request rate, 3 seconds ramp-up time, 0 delay
-		id,                   // id
-		100,                  // count
-		100*time.Millisecond, // requestRate
-		3*time.Second,        // rampUpTime
-		0,                    // delay
-		"",                   // behavior
-	)
+	return err
 }
 
 // stopSimulationTimer stops and clears the simulation stop timer if it exists
@@ -94,8 +246,8 @@ func (d *Dashboard) stopSimulationTimer() {
 	}
 }
 
-// ResetSimulation resets the simulation
-func (d *Dashboard) ResetSimulation() {
+// ResetSimulation resets the simulation, cancelling pending time limit and stopping the previous simulation first
+func (d *Dashboard) ResetSimulation() error {
 	log.Println("Dashboard: Reset simulation")
 	d.mu.Lock()
 	defer d.mu.Unlock()
@@ -103,45 +255,83 @@ func (d *Dashboard) ResetSimulation() {
 	d.stopSimulationTimer()
 
 	log.Println("Dashboard: Create new simulation before start")
-	d.resetSimulationUnsafe()
+	err := d.resetSimulationUnsafe()
+	if err != nil {
+		log.Printf("Dashboard: Warning: %v", err)
+	}
 
 	d.Notify("simulation_reset", nil)
+
+	return err
 }
 
-// StartSimulation starts the simulation, with optional time limit in seconds
-func (d *Dashboard) StartSimulation(limitSeconds ...int) {
+// StartSimulation starts the simulation, with optional time limit in seconds,
+// returns whether it was started, or it is already running and nothing was done
+func (d *Dashboard) StartSimulation(limitSeconds ...int) (started bool, err error) {
 	log.Println("Dashboard: Start simulation")
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// Stop any previous timer
-	d.stopSimulationTimer()
-
 	if d.simulation == nil {
 		log.Println("Dashboard: No simulation found, create new simulation before start")
-		d.resetSimulationUnsafe()
+		d.resetSimulationUnsafe() // Nothing to stop, can not fail
 	}
 
 	log.Println("Dashboard: Starting simulation...")
-	ctx := d.simulation.Start()
+	ctx, err := d.simulation.Start()
+	if err != nil {
+		log.Printf("Dashboard: Error starting simulation: %v", err)
+		return false, err
+	}
 
 	if ctx == nil {
 		log.Println("Dashboard: Simulation already running")
-		return
+		return false, nil
 	}
 
+	// Stop any previous timer, but not the one of already running simulation
+	d.stopSimulationTimer()
+
+	d.history.reset(d.simulation.Id, d.simulation.StartedAt())
 	d.metrics.WatchSimulationRun(ctx, d.simulation.GetMetricsSnapshot)
 
 	d.Notify("simulation_started", nil)
 
-	// If a limit is provided, schedule stop
+	// If a limit is provided, schedule stop.
+	// Timer could fire while simulation is being reset or restarted, so it stops simulation only if it is still the current timer
 	if len(limitSeconds) > 0 && limitSeconds[0] > 0 {
 		limit := time.Duration(limitSeconds[0]) * time.Second
-		d.stopTimer = time.AfterFunc(limit, func() {
+		var timer *time.Timer
+		timer = time.AfterFunc(limit, func() {
 			log.Printf("Dashboard: Simulation time limit (%ds) reached, stopping simulation", limitSeconds[0])
-			d.StopSimulation()
+			d.stopSimulationByTimer(timer)
 		})
+		d.stopTimer = timer
 	}
+
+	return true, nil
+}
+
+// PrepareSimulation creates clients of the next run beforehand, so that on start they fire at once without setup cost
+func (d *Dashboard) PrepareSimulation() error {
+	log.Println("Dashboard: Prepare simulation")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		log.Println("Dashboard: No simulation found, create new simulation before prepare")
+		d.resetSimulationUnsafe() // Nothing to stop, can not fail
+	}
+
+	err := d.simulation.Prepare()
+	if err != nil {
+		log.Printf("Dashboard: Error preparing simulation: %v", err)
+		return err
+	}
+
+	d.Notify("simulation_prepared", nil)
+
+	return nil
 }
 
 // StopSimulation stops the simulation
@@ -162,6 +352,78 @@ func (d *Dashboard) StopSimulation() {
 	d.Notify("simulation_stopped", nil)
 }
 
+// DrainSimulation stops the simulation gracefully, letting in-flight requests complete for up to timeout
+func (d *Dashboard) DrainSimulation(timeout time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Stop any running timer
+	d.stopSimulationTimer()
+
+	if d.simulation == nil {
+		return
+	}
+
+	log.Println("Dashboard: Draining simulation...")
+	d.simulation.StopDrain(timeout)
+
+	d.Notify("simulation_stopped", nil)
+}
+
+// PauseSimulation freezes request generation of the running simulation
+func (d *Dashboard) PauseSimulation() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	err := d.simulation.Pause()
+
+	if err == nil {
+		d.Notify("simulation_paused", nil)
+	}
+
+	return err
+}
+
+// ResumeSimulation resumes request generation of the paused simulation
+func (d *Dashboard) ResumeSimulation() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	err := d.simulation.Resume()
+
+	if err == nil {
+		d.Notify("simulation_resumed", nil)
+	}
+
+	return err
+}
+
+// stopSimulationByTimer stops the simulation by time limit, unless timer was cancelled by stop, start or reset meanwhile
+func (d *Dashboard) stopSimulationByTimer(timer *time.Timer) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stopTimer != timer || d.simulation == nil {
+		log.Println("Dashboard: Simulation time limit is outdated, ignoring")
+		return
+	}
+
+	d.stopTimer = nil
+
+	log.Println("Dashboard: Stopping simulation...")
+	d.simulation.Stop()
+
+	d.Notify("simulation_stopped", nil)
+}
+
 // startMetricsForwarding starts forwarding metrics from MetricsEmitter to WebSocketHub
 func (d *Dashboard) startMetricsForwarding() {
 	metricsCh := d.metrics.Subscribe(10)
@@ -169,6 +431,7 @@ func (d *Dashboard) startMetricsForwarding() {
 
 	for metrics := range metricsCh {
 		// log.Println("Dashboard: Metrics forwarding goroutine received metrics from metricsCh")
+		d.history.append(metrics)
 
 		metricsData, err := json.Marshal(metrics)
 		if err != nil {
@@ -181,6 +444,132 @@ func (d *Dashboard) startMetricsForwarding() {
 	}
 }
 
+// GetSimulationSettings returns the current simulation settings as DTO
+func (d *Dashboard) GetSimulationSettings() (SimulationSettingsJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return SimulationSettingsJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	return SimulationSettingsToJSON(d.simulation.GetSettings()), nil
+}
+
+// SetSimulationSettings sets the simulation settings from DTO
+func (d *Dashboard) SetSimulationSettings(settingsDTO SimulationSettingsJSON) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	err := d.simulation.SetSettings(SimulationSettingsFromJSON(settingsDTO))
+
+	if err == nil {
+		d.Notify("simulation_settings_updated", settingsDTO)
+	}
+
+	return err
+}
+
+// GetMetricsRecording returns whether metrics are being recorded as DTO
+func (d *Dashboard) GetMetricsRecording() (MetricsRecordingJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return MetricsRecordingJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	return MetricsRecordingJSON{Recording: d.simulation.IsMetricsRecording()}, nil
+}
+
+// SetMetricsRecording turns metrics recording on or off from DTO
+func (d *Dashboard) SetMetricsRecording(recordingDTO MetricsRecordingJSON) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	d.simulation.SetMetricsRecording(recordingDTO.Recording)
+
+	d.Notify("metrics_recording_updated", recordingDTO)
+
+	return nil
+}
+
+// GetCurves returns server and network behavior curves evaluated at resolution points each, keyed by curve name
+func (d *Dashboard) GetCurves(resolution int) (map[string][]CurveSampleJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	curves := d.simulation.SampleCurves(resolution)
+	result := make(map[string][]CurveSampleJSON, len(curves))
+	for name, samples := range curves {
+		result[name] = GenericMap(samples, CurveSampleToJSON)
+	}
+	return result, nil
+}
+
+// Validate checks the whole simulation configuration and returns all found issues
+func (d *Dashboard) Validate() (ValidationResultJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return ValidationResultJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	return ValidationResultToJSON(d.simulation.Validate()), nil
+}
+
+// MarkMetrics stores current metrics counters as a named baseline, replacing existing one with the same name
+func (d *Dashboard) MarkMetrics(markDTO MetricsMarkJSON) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	d.metricsBaselines[markDTO.Name] = metricsBaseline{
+		counters: d.simulation.GetMetricsCounters(),
+		markedAt: time.Now(),
+	}
+
+	return nil
+}
+
+// GetMetricsDiff returns metrics counters change since the named baseline, and current response time percentiles
+func (d *Dashboard) GetMetricsDiff(from string) (MetricsDiffJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return MetricsDiffJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	baseline, ok := d.metricsBaselines[from]
+	if !ok {
+		return MetricsDiffJSON{}, fmt.Errorf("Metrics baseline '%s' does not exist", from)
+	}
+
+	counters := d.simulation.GetMetricsCounters()
+	for key, value := range counters {
+		counters[key] = value - baseline.counters[key]
+	}
+
+	return MetricsDiffToJSON(from, time.Since(baseline.markedAt), counters, d.simulation.GetMetricsSnapshot()), nil
+}
+
 // GetClientConfigs returns the current client configs as DTOs
 func (d *Dashboard) GetClientConfigs() []ClientConfigJSON {
 	d.mu.Lock()
@@ -193,15 +582,7 @@ func (d *Dashboard) GetClientConfigs() []ClientConfigJSON {
 	configs := d.simulation.GetClientConfigs()
 	result := make([]ClientConfigJSON, 0, len(configs))
 	for _, config := range configs {
-		jsonConfig := ClientConfigJSON{
-			Id:          config.Id,
-			Count:       config.Count,
-			RequestRate: int(config.RequestRate / time.Millisecond),
-			RampUpTime:  int(config.RampUpTime / time.Millisecond),
-			Delay:       int(config.Delay / time.Millisecond),
-			Behavior:    config.Behavior,
-		}
-		result = append(result, jsonConfig)
+		result = append(result, ClientConfigToJSON(config))
 	}
 	return result
 }
@@ -215,14 +596,7 @@ func (d *Dashboard) AddClientConfig(config ClientConfigJSON) error {
 		return fmt.Errorf("Simulation does not exist")
 	}
 
-	err := d.simulation.AddClientsConfig(
-		config.Id,
-		config.Count,
-		time.Duration(config.RequestRate)*time.Millisecond,
-		time.Duration(config.RampUpTime)*time.Millisecond,
-		time.Duration(config.Delay)*time.Millisecond,
-		config.Behavior,
-	)
+	err := d.simulation.AddClientsConfig(ClientConfigFromJSON(config))
 
 	if err == nil {
 		d.Notify("client_config_added", config)
@@ -280,14 +654,7 @@ func (d *Dashboard) GetClientConfigById(id string) (ClientConfigJSON, error) {
 	if err != nil {
 		return ClientConfigJSON{}, err
 	}
-	return ClientConfigJSON{
-		Id:          config.Id,
-		Count:       config.Count,
-		RequestRate: int(config.RequestRate / time.Millisecond),
-		RampUpTime:  int(config.RampUpTime / time.Millisecond),
-		Delay:       int(config.Delay / time.Millisecond),
-		Behavior:    config.Behavior,
-	}, nil
+	return ClientConfigToJSON(config), nil
 }
 
 // UpdateClientConfig updates a client config by id from DTO
@@ -299,14 +666,7 @@ func (d *Dashboard) UpdateClientConfig(id string, config ClientConfigJSON) error
 		return fmt.Errorf("Simulation does not exist")
 	}
 
-	err := d.simulation.UpdateClientConfig(
-		id,
-		config.Count,
-		time.Duration(config.RequestRate)*time.Millisecond,
-		time.Duration(config.RampUpTime)*time.Millisecond,
-		time.Duration(config.Delay)*time.Millisecond,
-		config.Behavior,
-	)
+	err := d.simulation.UpdateClientConfig(id, ClientConfigFromJSON(config))
 
 	if err == nil {
 		d.Notify("client_config_updated", config)
@@ -344,6 +704,23 @@ func (d *Dashboard) SetServerBehavior(behaviorDTO ServerBehaviorJSON) error {
 	return nil
 }
 
+// TransitionServerBehavior moves the server behavior to the one from DTO gradually over the given duration
+func (d *Dashboard) TransitionServerBehavior(behaviorDTO ServerBehaviorJSON, duration time.Duration) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	behavior := ServerBehaviorFromJSON(behaviorDTO)
+	d.simulation.TransitionServerBehavior(behavior, duration)
+
+	d.Notify("server_behavior_updated", behaviorDTO)
+
+	return nil
+}
+
 // GetNetworkBehavior returns the current network behavior as internal struct, or error if simulation does not exist
 func (d *Dashboard) GetNetworkBehavior() (NetworkBehaviorJSON, error) {
 	d.mu.Lock()
@@ -372,3 +749,228 @@ func (d *Dashboard) SetNetworkBehavior(behaviorDTO NetworkBehaviorJSON) error {
 
 	return nil
 }
+
+// ExportConfig returns client configs, server and network behaviors as a single DTO
+func (d *Dashboard) ExportConfig() (SimulationConfigJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return SimulationConfigJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	return SimulationConfigJSON{
+		Clients: GenericMap(d.simulation.GetClientConfigs(), ClientConfigToJSON),
+		Server:  ServerBehaviorToJSON(d.simulation.GetServerBehavior()),
+		Network: NetworkBehaviorToJSON(d.simulation.GetNetworkBehavior()),
+	}, nil
+}
+
+// ImportConfig replaces client configs, server and network behaviors from DTO at once
+func (d *Dashboard) ImportConfig(configDTO SimulationConfigJSON) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	err := d.simulation.ReplaceConfig(
+		GenericMap(configDTO.Clients, ClientConfigFromJSON),
+		ServerBehaviorFromJSON(configDTO.Server),
+		NetworkBehaviorFromJSON(configDTO.Network),
+	)
+
+	if err == nil {
+		d.Notify("config_imported", nil)
+	}
+
+	return err
+}
+
+// Probe sends a single synthetic request through the simulation network and returns its outcome as DTO
+func (d *Dashboard) Probe(ctx context.Context) (ProbeResultJSON, error) {
+	d.mu.Lock()
+	sim := d.simulation
+	d.mu.Unlock()
+
+	if sim == nil {
+		return ProbeResultJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	result, err := sim.Probe(ctx)
+	if err != nil {
+		return ProbeResultJSON{}, err
+	}
+
+	return ProbeResultToJSON(result), nil
+}
+
+// EvaluateBehavior runs a single behavior hook against synthetic request, without running a simulation
+func (d *Dashboard) EvaluateBehavior(evalDTO BehaviorEvalJSON) (BehaviorEvalResultJSON, error) {
+	d.mu.Lock()
+	behaviorsDir := d.behaviorsDir
+	d.mu.Unlock()
+
+	result, err := simulation.EvaluateHook(behaviorsDir, BehaviorEvalFromJSON(evalDTO))
+	if err != nil {
+		return BehaviorEvalResultJSON{}, err
+	}
+
+	return BehaviorEvalResultToJSON(result), nil
+}
+
+// GetActiveBehaviors returns behavior scripts in effect for live clients as DTO
+func (d *Dashboard) GetActiveBehaviors() ([]ActiveBehaviorJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	return GenericMap(d.simulation.GetActiveBehaviors(), ActiveBehaviorToJSON), nil
+}
+
+// GetTimeline returns request arrivals captured from the current (or last) run as DTO
+func (d *Dashboard) GetTimeline() (RequestTimelineJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return RequestTimelineJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	return RequestTimelineToJSON(d.simulation.GetTimeline()), nil
+}
+
+// GetReplayTimeline returns the timeline replayed by clients as DTO, nil if clients send at request rate
+func (d *Dashboard) GetReplayTimeline() (*RequestTimelineJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	timeline := d.simulation.GetReplayTimeline()
+	if timeline == nil {
+		return nil, nil
+	}
+	timelineDTO := RequestTimelineToJSON(*timeline)
+	return &timelineDTO, nil
+}
+
+// SetReplayTimeline sets the timeline for the next runs to replay from DTO, nil sends requests at request rate again
+func (d *Dashboard) SetReplayTimeline(timelineDTO *RequestTimelineJSON) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return fmt.Errorf("Simulation does not exist")
+	}
+
+	var timeline *simulation.RequestTimeline
+	if timelineDTO != nil {
+		t := RequestTimelineFromJSON(*timelineDTO)
+		timeline = &t
+	}
+
+	err := d.simulation.SetReplayTimeline(timeline)
+	if err != nil {
+		return err
+	}
+
+	requests := 0
+	if timeline != nil {
+		requests = len(timeline.Requests)
+	}
+	d.Notify("replay_timeline_updated", map[string]any{"replay": timeline != nil, "requests": requests})
+
+	return nil
+}
+
+// GetMetricsSnapshot returns the current metrics snapshot of the simulation
+func (d *Dashboard) GetMetricsSnapshot() (map[string]any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	return d.simulation.GetMetricsSnapshot(), nil
+}
+
+// GetLatestMetricsSnapshot returns the last metrics snapshot broadcast during the running simulation,
+// or the current one if none was broadcast yet or the run is over (its final counters are not broadcast),
+// so that frequent readers do not take snapshots themselves
+func (d *Dashboard) GetLatestMetricsSnapshot() (map[string]any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	if d.simulation.IsRunning() {
+		if snapshot := d.history.latest(d.simulation.Id); snapshot != nil {
+			return snapshot, nil
+		}
+	}
+	return d.simulation.GetMetricsSnapshot(), nil
+}
+
+// CheckMetricsInvariants returns relationships between metrics counters which do not hold as DTO
+func (d *Dashboard) CheckMetricsInvariants() (MetricsInvariantsJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return MetricsInvariantsJSON{}, fmt.Errorf("Simulation does not exist")
+	}
+
+	violations := d.simulation.CheckMetricsInvariants()
+	return MetricsInvariantsJSON{
+		Ok:         len(violations) == 0,
+		Violations: GenericMap(violations, InvariantViolationToJSON),
+	}, nil
+}
+
+// GetMetricsHistory returns metrics snapshots retained for the latest run as DTO,
+// run id is optional and only checked to match the latest run
+func (d *Dashboard) GetMetricsHistory(runId string) (MetricsHistoryJSON, error) {
+	d.history.mu.Lock()
+	defer d.history.mu.Unlock()
+
+	if d.history.runId == "" {
+		return MetricsHistoryJSON{}, fmt.Errorf("No run has been recorded yet")
+	}
+	if runId != "" && runId != d.history.runId {
+		return MetricsHistoryJSON{}, fmt.Errorf("Run '%s' is not retained, history is kept for the latest run '%s' only", runId, d.history.runId)
+	}
+
+	snapshots := d.history.snapshots
+	if snapshots == nil {
+		snapshots = []map[string]any{}
+	}
+	return MetricsHistoryJSON{
+		RunId:     d.history.runId,
+		StartedAt: d.history.startedAt,
+		Dropped:   d.history.dropped,
+		Units:     d.history.units,
+		Snapshots: snapshots[:len(snapshots):len(snapshots)],
+	}, nil
+}
+
+// GetTimeSeries returns per-second rollup of the current (or last) run as DTOs
+func (d *Dashboard) GetTimeSeries() ([]TimeSeriesPointJSON, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.simulation == nil {
+		return nil, fmt.Errorf("Simulation does not exist")
+	}
+
+	return GenericMap(d.simulation.GetTimeSeries(), TimeSeriesPointToJSON), nil
+}
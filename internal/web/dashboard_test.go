@@ -0,0 +1,51 @@
+package web
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestResetSimulationWhileRunning resets the running simulation many times concurrently with starts, time limits
+// and metrics readers, run with -race to catch unsynchronized access to the simulation being replaced
+func TestResetSimulationWhileRunning(t *testing.T) {
+	d := NewDashboard()
+	if _, err := d.StartSimulation(1); err != nil {
+		t.Fatalf("start simulation: %v", err)
+	}
+
+	const rounds = 20
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range rounds {
+				if err := d.ResetSimulation(); err != nil {
+					t.Errorf("reset simulation: %v", err)
+				}
+				if _, err := d.StartSimulation(1); err != nil {
+					t.Errorf("start simulation: %v", err)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		})
+	}
+	wg.Go(func() {
+		for range rounds * 5 {
+			if _, err := d.GetMetricsSnapshot(); err != nil {
+				t.Errorf("get metrics snapshot: %v", err)
+			}
+			if _, err := d.GetLatestMetricsSnapshot(); err != nil {
+				t.Errorf("get latest metrics snapshot: %v", err)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	})
+	wg.Wait()
+
+	if err := d.ResetSimulation(); err != nil {
+		t.Fatalf("final reset simulation: %v", err)
+	}
+	if d.simulation == nil || d.simulation.IsRunning() {
+		t.Fatalf("expected a new stopped simulation after reset")
+	}
+}
@@ -5,10 +5,86 @@ import (
 	"time"
 )
 
+// defaultCapacityWarmupFraction is the share of capacity available right after start when warmup is enabled and
+// request does not set the fraction
+const defaultCapacityWarmupFraction = 0.1
+
 type SimulationJSON struct {
 	Id        *string `json:"id,omitempty"`
 	Status    Status  `json:"status"`
 	StartedAt int64   `json:"startedAt"`
+	Prepared  bool    `json:"prepared"` // Clients of the next run are prepared (warm pool)
+	Paused    bool    `json:"paused"`   // Running simulation does not generate new requests
+}
+
+type SimulationSettingsJSON struct {
+	StabilizationErrorRate float64 `json:"stabilizationErrorRate"`
+	TimeScale              float64 `json:"timeScale"`
+	Seed                   int64   `json:"seed"`
+	StarvationThresholdSec float64 `json:"starvationThresholdSec"`
+	ScriptQueueSize        int     `json:"scriptQueueSize"`
+	PercentileMethod       string  `json:"percentileMethod"` // nearest-rank | interpolated
+	GoodputDeadlineMs      int     `json:"goodputDeadlineMs"`
+	ScriptWorkers          int     `json:"scriptWorkers"` // 0 runs one executor goroutine per client
+
+	TimelineCapacity int `json:"timelineCapacity"` // 0 disables capture of request arrivals
+
+	MetricsWindowMs int `json:"metricsWindowMs"` // Sliding window of response time and latency metrics, 0 uses 1s
+
+	SLOLatencyMs int `json:"sloLatencyMs"` // 0 disables counting of SLO violations
+
+	ApdexThresholdMs int `json:"apdexThresholdMs"` // 0 disables the Apdex score
+}
+
+type MetricsRecordingJSON struct {
+	Recording bool `json:"recording"`
+}
+
+type CurveSampleJSON struct {
+	XMs float64 `json:"x_ms"`
+	Y   float64 `json:"y"`
+}
+
+type ValidationIssueJSON struct {
+	Severity string `json:"severity"` // warning | error
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+type ValidationResultJSON struct {
+	Valid  bool                  `json:"valid"` // No errors, there still can be warnings
+	Issues []ValidationIssueJSON `json:"issues"`
+}
+
+type MetricsMarkJSON struct {
+	Name string `json:"name"`
+}
+
+type MetricsHistoryJSON struct {
+	RunId     string            `json:"runId"`
+	StartedAt int64             `json:"startedAt"`
+	Dropped   int               `json:"dropped"` // Oldest snapshots dropped because of capacity
+	Units     map[string]string `json:"units"`
+	Snapshots []map[string]any  `json:"snapshots"`
+}
+
+type MetricsDiffJSON struct {
+	From              string           `json:"from"`
+	ElapsedMs         int64            `json:"elapsedMs"` // Time since the baseline was marked
+	Counters          map[string]int64 `json:"counters"`  // Counters change since the baseline
+	P50ResponseTimeMs int64            `json:"p50ResponseTimeMs"`
+	P80ResponseTimeMs int64            `json:"p80ResponseTimeMs"`
+	P95ResponseTimeMs int64            `json:"p95ResponseTimeMs"`
+}
+
+type MetricsInvariantsJSON struct {
+	Ok         bool                     `json:"ok"`
+	Violations []InvariantViolationJSON `json:"violations"`
+}
+
+type InvariantViolationJSON struct {
+	Invariant string           `json:"invariant"`
+	Values    map[string]int64 `json:"values"` // Counters involved in the invariant
 }
 
 type ClientConfigJSON struct {
@@ -16,8 +92,63 @@ type ClientConfigJSON struct {
 	Count       int    `json:"count"`
 	RequestRate int    `json:"requestRate"`
 	RampUpTime  int    `json:"rampUpTime"`
+	RampUpMode  string `json:"rampUpMode"` // gradual | immediate | herd
 	Delay       int    `json:"startupDelay"`
 	Behavior    string `json:"behavior"`
+	// Probability that the very first request of each client fails to connect
+	FirstRequestFailureRate float64 `json:"firstRequestFailureRate"`
+	// Built-in retries, for behaviors without `on_retry` hook
+	RetryOnServerError  bool `json:"retryOnServerError"`
+	RetryOnNetworkError bool `json:"retryOnNetworkError"`
+	MaxRetries          int  `json:"maxRetries"` // 3 if not positive
+	RetryDelay          int  `json:"retryDelay"`
+	// Backoff of built-in retries, retry delay is its base
+	RetryJitterMode string `json:"retryJitterMode"` // none | full | equal | decorrelated
+	MaxRetryDelay   int    `json:"maxRetryDelay"`   // 0 is unlimited
+	// Pause of each client's requests after a failed request, ms (0 disables)
+	ErrorCooldownMs int `json:"errorCooldownMs"`
+	// AIMD control of each client's request rate
+	AdaptiveRate *AdaptiveRateJSON `json:"adaptiveRate,omitempty"`
+	// Number of logical operations batched into one request (0 or 1 disables)
+	BatchSize int `json:"batchSize"`
+	// Distribution of intervals between requests
+	ArrivalModel string `json:"arrivalModel"` // fixed | poisson
+	// Distribution of request body sizes, sampled per request (empty uses size of request data)
+	PayloadSizes []PayloadSizeJSON `json:"payloadSizes,omitempty"`
+	// Closed workload waits for outcome of each request, then thinks before the next one (request rate is not used)
+	WorkloadModel string `json:"workloadModel"` // open | closed
+	ThinkTimeMs   int    `json:"thinkTimeMs"`
+	// Weighted kinds of requests, sampled per request and visible to behavior scripts (empty sends requests without kind)
+	RequestKinds []RequestKindJSON `json:"requestKinds,omitempty"`
+	// Default request timeout, ms, if behavior does not set one (0 waits indefinitely), and its jitter in percent
+	TimeoutMs        int     `json:"timeoutMs"`
+	TimeoutJitterPct float64 `json:"timeoutJitterPct"`
+	// Consecutive failed requests after which a client abandons its session (0 disables)
+	AbandonAfterFailures int `json:"abandonAfterFailures"`
+	// Resolved behavior script source (read-only, set on start)
+	BehaviorSource string `json:"behaviorSource,omitempty"`
+}
+
+type PayloadSizeJSON struct {
+	SizeBytes int     `json:"sizeBytes"`
+	Weight    float64 `json:"weight"` // Relative frequency
+}
+
+type RequestKindJSON struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`         // Relative frequency
+	Data   string  `json:"data,omitempty"` // Request data of this kind (empty uses default data)
+
+	CPUCost float64 `json:"cpuCost,omitempty"` // CPU weight relative to a default request (0 uses 1)
+}
+
+type AdaptiveRateJSON struct {
+	Enabled         bool    `json:"enabled"`
+	TargetLatencyMs int     `json:"targetLatencyMs"` // 0 decreases on failures only
+	IncreaseRPS     float64 `json:"increaseRps"`     // 1 if not positive
+	DecreaseFactor  float64 `json:"decreaseFactor"`  // 0.5 if not set
+	MinRPS          float64 `json:"minRps"`          // 0.1 if not positive
+	MaxRPS          float64 `json:"maxRps"`          // 0 is unlimited
 }
 
 type BehaviorPointJSON struct {
@@ -26,25 +157,78 @@ type BehaviorPointJSON struct {
 	Type string  `json:"type"` // curve | break
 }
 
+type LatencySpikeJSON struct {
+	AtSec          float64 `json:"atSec"`
+	DurationSec    float64 `json:"durationSec"`
+	ExtraLatencyMs float64 `json:"extraLatencyMs"`
+	Dimension      string  `json:"dimension"` // all | request | response (network only)
+}
+
 type ServerResourcesJSON struct {
 	MaxConcurrentRequests  int     `json:"maxConcurrentRequests"`
 	MaxMemoryMB            int     `json:"maxMemoryMB"`
 	MaxQueueSize           int     `json:"maxQueueSize"`
 	MemoryLeakRateMBPerSec float64 `json:"memoryLeakRateMBPerSec"`
 	MemoryPerRequestMB     float64 `json:"memoryPerRequestMB"`
+	MemoryPerResponseKB    float64 `json:"memoryPerResponseKB"` // Per KB of buffered response body (0 disables)
 	GCPauseIntervalSec     float64 `json:"gcPauseIntervalSec"`
 	GCPauseDurationMs      float64 `json:"gcPauseDurationMs"`
+	// Processing time multiplier increase per queued request ahead (0 disables)
+	QueueDepthLatencyFactor float64 `json:"queueDepthLatencyFactor"`
+	// Fraction of IO-bound requests and IO pool size (0 disables)
+	IOBoundFraction float64 `json:"ioBoundFraction"`
+	MaxConcurrentIO int     `json:"maxConcurrentIO"`
+	// Worker dispatch queue size between accept queue and workers (0 disables)
+	MaxDispatchQueueSize int `json:"maxDispatchQueueSize"`
+	// Memory utilization above which server swaps, and response time multiplier when it does (0 disables)
+	SwapThreshold         float64 `json:"swapThreshold"`
+	SwapLatencyMultiplier float64 `json:"swapLatencyMultiplier"`
+	// Weights of CPU, memory and thread impacts (all 0 keeps equal weights), summed when additive, and multiplier cap (0 disables)
+	CPUImpactWeight             float64 `json:"cpuImpactWeight"`
+	MemoryImpactWeight          float64 `json:"memoryImpactWeight"`
+	ThreadsImpactWeight         float64 `json:"threadsImpactWeight"`
+	AdditiveResourceImpact      bool    `json:"additiveResourceImpact"`
+	MaxResourceImpactMultiplier float64 `json:"maxResourceImpactMultiplier"`
 }
 
 type ServerBehaviorJSON struct {
-	To                       int                 `json:"to"`
-	ResponseTimeFrom         int                 `json:"rtfrom"`
-	ResponseTimeTo           int                 `json:"rtto"`
-	ReponseTimeMin           []BehaviorPointJSON `json:"rtmin"`
-	ReponseTimeMax           []BehaviorPointJSON `json:"rtmax"`
-	Errors                   []BehaviorPointJSON `json:"errors"`
-	EnableResourceManagement bool                `json:"enableResourceManagement"`
-	Resources                ServerResourcesJSON `json:"resources"`
+	To                       int                          `json:"to"`
+	ResponseTimeFrom         int                          `json:"rtfrom"`
+	ResponseTimeTo           int                          `json:"rtto"`
+	ReponseTimeMin           []BehaviorPointJSON          `json:"rtmin"`
+	ReponseTimeMax           []BehaviorPointJSON          `json:"rtmax"`
+	Errors                   []BehaviorPointJSON          `json:"errors"`
+	EnableResourceManagement bool                         `json:"enableResourceManagement"`
+	Resources                ServerResourcesJSON          `json:"resources"`
+	CapacityRPS              float64                      `json:"capacityRps"`
+	IdempotencyWindowMs      int                          `json:"idempotencyWindowMs"`
+	CapacityWarmupSec        float64                      `json:"capacityWarmupSec"`
+	CapacityWarmupFraction   float64                      `json:"capacityWarmupFraction"`
+	ResponseSizeBytes        int                          `json:"responseSizeBytes"`
+	GroupOverrides           map[string]GroupOverrideJSON `json:"groupOverrides,omitempty"`
+	FanOutBackends           int                          `json:"fanOutBackends"`
+	FanOutBackendErrorRate   float64                      `json:"fanOutBackendErrorRate"`
+	FanOutQuorum             int                          `json:"fanOutQuorum"` // 0 requires all backends
+	LatencyCorrelation       float64                      `json:"latencyCorrelation"`
+	SerialFraction           float64                      `json:"serialFraction"`
+	Script                   string                       `json:"script,omitempty"` // Starlark script with on_handle(req) hook
+	// Shed retries first under pressure (requires resource management)
+	DeprioritizeRetries bool `json:"deprioritizeRetries"`
+	// Keep sampled response time within [min, max] of the curves
+	TruncateToRange bool `json:"truncateToRange"`
+	// Concurrent requests with the same key share one processing and its result (requests without key are not coalesced)
+	SingleFlight bool `json:"singleFlight"`
+	// Extra processing time during scheduled windows
+	LatencySpikes []LatencySpikeJSON `json:"latencySpikes,omitempty"`
+	// Body of successful responses with {request_id}, {trace_id}, {client_id}, {group}, {kind} and {elapsed_ms}
+	// substituted, templates by request kind take precedence (empty responds "OK")
+	ResponseTemplate        string            `json:"responseTemplate"`
+	ResponseTemplatesByKind map[string]string `json:"responseTemplatesByKind,omitempty"`
+}
+
+type GroupOverrideJSON struct {
+	ResponseTimeMultiplier *float64 `json:"responseTimeMultiplier,omitempty"` // 1 if omitted
+	ErrorRateMultiplier    *float64 `json:"errorRateMultiplier,omitempty"`    // 1 if omitted
 }
 
 type ServerResourceMetricsJSON struct {
@@ -59,12 +243,163 @@ type ServerResourceMetricsJSON struct {
 }
 
 type NetworkBehaviorJSON struct {
-	To          int                 `json:"to"`
-	LatencyFrom int                 `json:"latfrom"`
-	LatencyTo   int                 `json:"latto"`
-	DropRate    []BehaviorPointJSON `json:"drops"`
-	LatencyMin  []BehaviorPointJSON `json:"latmin"`
-	LatencyMax  []BehaviorPointJSON `json:"latmax"`
+	To                   int                 `json:"to"`
+	LatencyFrom          int                 `json:"latfrom"`
+	LatencyTo            int                 `json:"latto"`
+	DropRate             []BehaviorPointJSON `json:"drops"`
+	LatencyMin           []BehaviorPointJSON `json:"latmin"`
+	LatencyMax           []BehaviorPointJSON `json:"latmax"`
+	BandwidthBytesPerSec int                 `json:"bandwidthBytesPerSec"`
+	BlackHoleRate        float64             `json:"blackHoleRate"`
+	// Keep sampled latency within [min, max] of the curves
+	TruncateToRange bool `json:"truncateToRange"`
+	// One-time delays of a new connection, and idle time after which it is closed (0 keeps it open)
+	ConnectionSetupMs       int `json:"connectionSetupMs"`
+	TLSHandshakeMs          int `json:"tlsHandshakeMs"`
+	ConnectionIdleTimeoutMs int `json:"connectionIdleTimeoutMs"`
+	// Latency curves of the response leg, request latency curves are used if omitted
+	ResponseLatencyMin []BehaviorPointJSON `json:"resplatmin,omitempty"`
+	ResponseLatencyMax []BehaviorPointJSON `json:"resplatmax,omitempty"`
+	// Weight of previous latency in each new sample, in [0, 1) (0 disables)
+	LatencyCorrelation float64 `json:"latencyCorrelation"`
+	// Probability of holding response over a reused connection until a later response is delivered, and max delay it is held for (0 disables)
+	ReorderRate       float64 `json:"reorderRate"`
+	ReorderMaxDelayMs int     `json:"reorderMaxDelayMs"`
+	// Extra latency during scheduled windows
+	LatencySpikes []LatencySpikeJSON `json:"latencySpikes,omitempty"`
+}
+
+type ProbeResultJSON struct {
+	RequestId         string  `json:"requestId"`
+	TraceId           string  `json:"traceId"`
+	Ok                bool    `json:"ok"`
+	Outcome           string  `json:"outcome"`
+	Data              string  `json:"data,omitempty"`
+	Error             string  `json:"error,omitempty"`
+	RetryAfterMs      int     `json:"retryAfterMs,omitempty"`
+	ConnectionSetupMs float64 `json:"connectionSetupMs"`
+	RequestLatencyMs  float64 `json:"requestLatencyMs"`
+	ProcessingTimeMs  float64 `json:"processingTimeMs"`
+	ResponseLatencyMs float64 `json:"responseLatencyMs"`
+	ReorderDelayMs    float64 `json:"reorderDelayMs"`
+	TotalTimeMs       float64 `json:"totalTimeMs"`
+}
+
+type BehaviorEvalJSON struct {
+	Script   string                  `json:"script"`
+	Hook     string                  `json:"hook"`
+	Request  BehaviorEvalRequestJSON `json:"request"`
+	Response *ProbeResultJSON        `json:"response,omitempty"` // Synthetic response, only fields known to scripts are used
+	Error    string                  `json:"error,omitempty"`    // Error passed to on_fail and on_retry
+}
+
+type BehaviorEvalRequestJSON struct {
+	Id             string         `json:"id"`
+	ClientId       string         `json:"clientId"`
+	Group          string         `json:"group,omitempty"`
+	Data           string         `json:"data"`
+	Meta           map[string]any `json:"meta,omitempty"`
+	IdempotencyKey string         `json:"idempotencyKey,omitempty"`
+	TraceId        string         `json:"traceId,omitempty"`
+	Kind           string         `json:"kind,omitempty"`
+}
+
+type BehaviorEvalResultJSON struct {
+	Allow          bool           `json:"allow"`
+	DelayMs        int            `json:"delayMs"`
+	TimeoutMs      int            `json:"timeoutMs"`
+	Meta           map[string]any `json:"meta"`
+	IdempotencyKey string         `json:"idempotencyKey,omitempty"`
+	TraceId        string         `json:"traceId,omitempty"`
+	Output         []string       `json:"output"`
+	Error          string         `json:"error,omitempty"`
+}
+
+type ActiveBehaviorJSON struct {
+	Group         string `json:"group"`
+	Source        string `json:"source"`
+	Hash          string `json:"hash,omitempty"` // Short hash of the source (empty if there is no script)
+	Compiled      bool   `json:"compiled"`
+	Error         string `json:"error,omitempty"` // Compile error, clients fell back to noop behavior
+	Clients       int    `json:"clients"`
+	ConfigChanged bool   `json:"configChanged"` // Group configuration has a different script now
+}
+
+// SimulationConfigJSON is a complete scenario, which can be saved to a file and loaded back
+type SimulationConfigJSON struct {
+	Clients []ClientConfigJSON  `json:"clients"`
+	Server  ServerBehaviorJSON  `json:"server"`
+	Network NetworkBehaviorJSON `json:"network"`
+}
+
+type TimelineRequestJSON struct {
+	OffsetMs  float64 `json:"offsetMs"` // Time from simulation start
+	ClientId  string  `json:"clientId"`
+	Group     string  `json:"group"`
+	TraceId   string  `json:"traceId,omitempty"`
+	SizeBytes int     `json:"sizeBytes,omitempty"` // Sampled payload size
+	Kind      string  `json:"kind,omitempty"`      // Sampled request kind
+}
+
+type RequestTimelineJSON struct {
+	Requests  []TimelineRequestJSON `json:"requests"`
+	Truncated bool                  `json:"truncated"` // Capacity was reached, later arrivals are not captured
+}
+
+type TimeSeriesPointJSON struct {
+	Timestamp         int64   `json:"timestamp"`
+	ElapsedMs         int64   `json:"elapsedMs"`
+	RPS               float64 `json:"rps"`
+	ErrorRate         float64 `json:"errorRate"`
+	P50ResponseTimeMs float64 `json:"p50ResponseTimeMs"`
+	P95ResponseTimeMs float64 `json:"p95ResponseTimeMs"`
+	P99ResponseTimeMs float64 `json:"p99ResponseTimeMs"`
+	CPUUtilization    float64 `json:"cpuUtilization"`
+	MemoryUtilization float64 `json:"memoryUtilization"`
+}
+
+func CurveSampleToJSON(cs simulation.CurveSample) CurveSampleJSON {
+	return CurveSampleJSON{
+		XMs: cs.X,
+		Y:   cs.Y,
+	}
+}
+
+func ValidationIssueToJSON(vi simulation.ValidationIssue) ValidationIssueJSON {
+	return ValidationIssueJSON{
+		Severity: vi.Severity.String(),
+		Path:     vi.Path,
+		Message:  vi.Message,
+	}
+}
+
+func ValidationResultToJSON(issues []simulation.ValidationIssue) ValidationResultJSON {
+	issuesJSON := make([]ValidationIssueJSON, 0, len(issues))
+	valid := true
+	for _, issue := range issues {
+		issuesJSON = append(issuesJSON, ValidationIssueToJSON(issue))
+		if issue.Severity == simulation.ValidationError {
+			valid = false
+		}
+	}
+	return ValidationResultJSON{
+		Valid:  valid,
+		Issues: issuesJSON,
+	}
+}
+
+func MetricsDiffToJSON(from string, elapsed time.Duration, counters map[string]int64, snapshot map[string]any) MetricsDiffJSON {
+	p50, _ := snapshot["p50_response_time_ms"].(int64)
+	p80, _ := snapshot["p80_response_time_ms"].(int64)
+	p95, _ := snapshot["p95_response_time_ms"].(int64)
+	return MetricsDiffJSON{
+		From:              from,
+		ElapsedMs:         elapsed.Milliseconds(),
+		Counters:          counters,
+		P50ResponseTimeMs: p50,
+		P80ResponseTimeMs: p80,
+		P95ResponseTimeMs: p95,
+	}
 }
 
 func ClientConfigsDto(d *Dashboard) []ClientConfigJSON {
@@ -76,24 +411,184 @@ func ClientConfigsDto(d *Dashboard) []ClientConfigJSON {
 	result := make([]ClientConfigJSON, 0, len(configs))
 
 	for _, config := range configs {
-		jsonConfig := ClientConfigJSON{
-			Id:          config.Id,
-			Count:       config.Count,
-			RequestRate: int(config.RequestRate / time.Millisecond),
-			RampUpTime:  int(config.RampUpTime / time.Millisecond),
-			Delay:       int(config.Delay / time.Millisecond),
-			Behavior:    config.Behavior,
-		}
-		result = append(result, jsonConfig)
+		result = append(result, ClientConfigToJSON(config))
 	}
 
 	return result
 }
 
+func ClientConfigToJSON(config simulation.ClientConfig) ClientConfigJSON {
+	return ClientConfigJSON{
+		Id:             config.Id,
+		Count:          config.Count,
+		RequestRate:    int(config.RequestRate / time.Millisecond),
+		RampUpTime:     int(config.RampUpTime / time.Millisecond),
+		RampUpMode:     config.RampUpMode.String(),
+		Delay:          int(config.Delay / time.Millisecond),
+		Behavior:       config.Behavior,
+		BehaviorSource: config.BehaviorSource,
+
+		FirstRequestFailureRate: config.FirstRequestFailureRate,
+
+		RetryOnServerError:  config.RetryOnServerError,
+		RetryOnNetworkError: config.RetryOnNetworkError,
+		MaxRetries:          config.MaxRetries,
+		RetryDelay:          int(config.RetryDelay / time.Millisecond),
+		RetryJitterMode:     config.JitterMode.String(),
+		MaxRetryDelay:       int(config.MaxRetryDelay / time.Millisecond),
+
+		ErrorCooldownMs: int(config.ErrorCooldown / time.Millisecond),
+		AdaptiveRate:    AdaptiveRateToJSON(config.AdaptiveRate),
+		BatchSize:       config.BatchSize,
+		ArrivalModel:    config.ArrivalModel.String(),
+		PayloadSizes:    GenericMap(config.PayloadSizes, PayloadSizeToJSON),
+		WorkloadModel:   config.WorkloadModel.String(),
+		ThinkTimeMs:     int(config.ThinkTime / time.Millisecond),
+		RequestKinds:    GenericMap(config.RequestKinds, RequestKindToJSON),
+
+		TimeoutMs:        int(config.Timeout / time.Millisecond),
+		TimeoutJitterPct: config.TimeoutJitterPct,
+
+		AbandonAfterFailures: config.AbandonAfterFailures,
+	}
+}
+
+func PayloadSizeToJSON(ps simulation.PayloadSize) PayloadSizeJSON {
+	return PayloadSizeJSON{
+		SizeBytes: ps.SizeBytes,
+		Weight:    ps.Weight,
+	}
+}
+
+func PayloadSizeFromJSON(psj PayloadSizeJSON) simulation.PayloadSize {
+	return simulation.PayloadSize{
+		SizeBytes: psj.SizeBytes,
+		Weight:    psj.Weight,
+	}
+}
+
+func RequestKindToJSON(rk simulation.RequestKind) RequestKindJSON {
+	return RequestKindJSON{
+		Name:    rk.Name,
+		Weight:  rk.Weight,
+		Data:    rk.Data,
+		CPUCost: rk.CPUCost,
+	}
+}
+
+func RequestKindFromJSON(rkj RequestKindJSON) simulation.RequestKind {
+	return simulation.RequestKind{
+		Name:    rkj.Name,
+		Weight:  rkj.Weight,
+		Data:    rkj.Data,
+		CPUCost: rkj.CPUCost,
+	}
+}
+
+func AdaptiveRateToJSON(ar simulation.AdaptiveRate) *AdaptiveRateJSON {
+	if !ar.Enabled {
+		return nil
+	}
+	return &AdaptiveRateJSON{
+		Enabled:         ar.Enabled,
+		TargetLatencyMs: int(ar.TargetLatency / time.Millisecond),
+		IncreaseRPS:     ar.IncreaseRPS,
+		DecreaseFactor:  ar.DecreaseFactor,
+		MinRPS:          ar.MinRPS,
+		MaxRPS:          ar.MaxRPS,
+	}
+}
+
+func AdaptiveRateFromJSON(arj *AdaptiveRateJSON) simulation.AdaptiveRate {
+	if arj == nil {
+		return simulation.AdaptiveRate{}
+	}
+	return simulation.AdaptiveRate{
+		Enabled:        arj.Enabled,
+		TargetLatency:  time.Duration(arj.TargetLatencyMs) * time.Millisecond,
+		IncreaseRPS:    arj.IncreaseRPS,
+		DecreaseFactor: arj.DecreaseFactor,
+		MinRPS:         arj.MinRPS,
+		MaxRPS:         arj.MaxRPS,
+	}
+}
+
+func ClientConfigFromJSON(configJSON ClientConfigJSON) simulation.ClientConfig {
+	var rampUpMode simulation.RampUpMode
+	switch configJSON.RampUpMode {
+	case "immediate":
+		rampUpMode = simulation.RampUpImmediate
+	case "herd":
+		rampUpMode = simulation.RampUpHerd
+	default:
+		rampUpMode = simulation.RampUpGradual // fallback
+	}
+	var jitterMode simulation.RetryJitterMode
+	switch configJSON.RetryJitterMode {
+	case "full":
+		jitterMode = simulation.RetryJitterFull
+	case "equal":
+		jitterMode = simulation.RetryJitterEqual
+	case "decorrelated":
+		jitterMode = simulation.RetryJitterDecorrelated
+	default:
+		jitterMode = simulation.RetryJitterNone // fallback
+	}
+	var arrivalModel simulation.ArrivalModel
+	switch configJSON.ArrivalModel {
+	case "poisson":
+		arrivalModel = simulation.ArrivalPoisson
+	default:
+		arrivalModel = simulation.ArrivalFixed // fallback
+	}
+	var workloadModel simulation.WorkloadModel
+	switch configJSON.WorkloadModel {
+	case "closed":
+		workloadModel = simulation.WorkloadClosed
+	default:
+		workloadModel = simulation.WorkloadOpen // fallback
+	}
+	return simulation.ClientConfig{
+		Id:          configJSON.Id,
+		Count:       configJSON.Count,
+		RequestRate: time.Duration(configJSON.RequestRate) * time.Millisecond,
+		RampUpTime:  time.Duration(configJSON.RampUpTime) * time.Millisecond,
+		RampUpMode:  rampUpMode,
+		Delay:       time.Duration(configJSON.Delay) * time.Millisecond,
+		Behavior:    configJSON.Behavior,
+
+		FirstRequestFailureRate: configJSON.FirstRequestFailureRate,
+
+		RetryPolicy: simulation.RetryPolicy{
+			RetryOnServerError:  configJSON.RetryOnServerError,
+			RetryOnNetworkError: configJSON.RetryOnNetworkError,
+			MaxRetries:          configJSON.MaxRetries,
+			RetryDelay:          time.Duration(configJSON.RetryDelay) * time.Millisecond,
+			JitterMode:          jitterMode,
+			MaxRetryDelay:       time.Duration(configJSON.MaxRetryDelay) * time.Millisecond,
+		},
+		ErrorCooldown: time.Duration(configJSON.ErrorCooldownMs) * time.Millisecond,
+		AdaptiveRate:  AdaptiveRateFromJSON(configJSON.AdaptiveRate),
+		BatchSize:     configJSON.BatchSize,
+		ArrivalModel:  arrivalModel,
+		PayloadSizes:  GenericMap(configJSON.PayloadSizes, PayloadSizeFromJSON),
+		WorkloadModel: workloadModel,
+		ThinkTime:     time.Duration(configJSON.ThinkTimeMs) * time.Millisecond,
+		RequestKinds:  GenericMap(configJSON.RequestKinds, RequestKindFromJSON),
+
+		Timeout:          time.Duration(configJSON.TimeoutMs) * time.Millisecond,
+		TimeoutJitterPct: configJSON.TimeoutJitterPct,
+
+		AbandonAfterFailures: configJSON.AbandonAfterFailures,
+	}
+}
+
 func SimulationDto(d *Dashboard) SimulationJSON {
 	var id *string
 	var status Status
 	var startedAt int64
+	var prepared bool
+	var paused bool
 
 	simulation := d.simulation
 	if simulation == nil {
@@ -108,12 +603,57 @@ func SimulationDto(d *Dashboard) SimulationJSON {
 			status = StatusStopped
 		}
 		startedAt = simulation.StartedAt()
+		prepared = simulation.IsPrepared()
+		paused = simulation.IsPaused()
 	}
 
 	return SimulationJSON{
 		Id:        id,
 		Status:    status,
 		StartedAt: startedAt,
+		Prepared:  prepared,
+		Paused:    paused,
+	}
+}
+
+func SimulationSettingsToJSON(ss simulation.Settings) SimulationSettingsJSON {
+	return SimulationSettingsJSON{
+		StabilizationErrorRate: ss.StabilizationErrorRate,
+		TimeScale:              ss.TimeScale,
+		Seed:                   ss.Seed,
+		StarvationThresholdSec: ss.StarvationThresholdSec,
+		ScriptQueueSize:        ss.ScriptQueueSize,
+		PercentileMethod:       ss.PercentileMethod.String(),
+		GoodputDeadlineMs:      ss.GoodputDeadlineMs,
+		ScriptWorkers:          ss.ScriptWorkers,
+		TimelineCapacity:       ss.TimelineCapacity,
+		MetricsWindowMs:        ss.MetricsWindowMs,
+		SLOLatencyMs:           ss.SLOLatencyMs,
+		ApdexThresholdMs:       ss.ApdexThresholdMs,
+	}
+}
+
+func SimulationSettingsFromJSON(ssj SimulationSettingsJSON) simulation.Settings {
+	var pm simulation.PercentileMethod
+	switch ssj.PercentileMethod {
+	case "interpolated":
+		pm = simulation.PercentileInterpolated
+	default:
+		pm = simulation.PercentileNearestRank // fallback
+	}
+	return simulation.Settings{
+		StabilizationErrorRate: ssj.StabilizationErrorRate,
+		TimeScale:              ssj.TimeScale,
+		Seed:                   ssj.Seed,
+		StarvationThresholdSec: ssj.StarvationThresholdSec,
+		ScriptQueueSize:        ssj.ScriptQueueSize,
+		PercentileMethod:       pm,
+		GoodputDeadlineMs:      ssj.GoodputDeadlineMs,
+		ScriptWorkers:          ssj.ScriptWorkers,
+		TimelineCapacity:       ssj.TimelineCapacity,
+		MetricsWindowMs:        ssj.MetricsWindowMs,
+		SLOLatencyMs:           ssj.SLOLatencyMs,
+		ApdexThresholdMs:       ssj.ApdexThresholdMs,
 	}
 }
 
@@ -147,12 +687,26 @@ func NetworkBehaviorToJSON(nb simulation.NetworkBehavior) NetworkBehaviorJSON {
 	latencyMin := GenericMap(nb.LatencyMin, BehaviorPointToJSON)
 	latencyMax := GenericMap(nb.LatencyMax, BehaviorPointToJSON)
 	return NetworkBehaviorJSON{
-		To:          nb.To,
-		LatencyFrom: nb.LatencyFrom,
-		LatencyTo:   nb.LatencyTo,
-		DropRate:    dropRate,
-		LatencyMin:  latencyMin,
-		LatencyMax:  latencyMax,
+		To:                   nb.To,
+		LatencyFrom:          nb.LatencyFrom,
+		LatencyTo:            nb.LatencyTo,
+		DropRate:             dropRate,
+		LatencyMin:           latencyMin,
+		LatencyMax:           latencyMax,
+		BandwidthBytesPerSec: nb.BandwidthBytesPerSec,
+		BlackHoleRate:        nb.BlackHoleRate,
+		TruncateToRange:      nb.TruncateToRange,
+
+		ConnectionSetupMs:       nb.ConnectionSetupMs,
+		TLSHandshakeMs:          nb.TLSHandshakeMs,
+		ConnectionIdleTimeoutMs: nb.ConnectionIdleTimeoutMs,
+
+		ResponseLatencyMin: GenericMap(nb.ResponseLatencyMin, BehaviorPointToJSON),
+		ResponseLatencyMax: GenericMap(nb.ResponseLatencyMax, BehaviorPointToJSON),
+		LatencyCorrelation: nb.LatencyCorrelation,
+		ReorderRate:        nb.ReorderRate,
+		ReorderMaxDelayMs:  nb.ReorderMaxDelayMs,
+		LatencySpikes:      GenericMap(nb.LatencySpikes, LatencySpikeToJSON),
 	}
 }
 
@@ -161,12 +715,53 @@ func NetworkBehaviorFromJSON(nbj NetworkBehaviorJSON) simulation.NetworkBehavior
 	latencyMin := GenericMap(nbj.LatencyMin, BehaviorPointFromJSON)
 	latencyMax := GenericMap(nbj.LatencyMax, BehaviorPointFromJSON)
 	return simulation.NetworkBehavior{
-		To:          nbj.To,
-		LatencyFrom: nbj.LatencyFrom,
-		LatencyTo:   nbj.LatencyTo,
-		DropRate:    dropRate,
-		LatencyMin:  latencyMin,
-		LatencyMax:  latencyMax,
+		To:                   nbj.To,
+		LatencyFrom:          nbj.LatencyFrom,
+		LatencyTo:            nbj.LatencyTo,
+		DropRate:             dropRate,
+		LatencyMin:           latencyMin,
+		LatencyMax:           latencyMax,
+		BandwidthBytesPerSec: nbj.BandwidthBytesPerSec,
+		BlackHoleRate:        nbj.BlackHoleRate,
+		TruncateToRange:      nbj.TruncateToRange,
+
+		ConnectionSetupMs:       nbj.ConnectionSetupMs,
+		TLSHandshakeMs:          nbj.TLSHandshakeMs,
+		ConnectionIdleTimeoutMs: nbj.ConnectionIdleTimeoutMs,
+
+		ResponseLatencyMin: GenericMap(nbj.ResponseLatencyMin, BehaviorPointFromJSON),
+		ResponseLatencyMax: GenericMap(nbj.ResponseLatencyMax, BehaviorPointFromJSON),
+		LatencyCorrelation: nbj.LatencyCorrelation,
+		ReorderRate:        nbj.ReorderRate,
+		ReorderMaxDelayMs:  nbj.ReorderMaxDelayMs,
+		LatencySpikes:      GenericMap(nbj.LatencySpikes, LatencySpikeFromJSON),
+	}
+}
+
+func LatencySpikeToJSON(ls simulation.LatencySpike) LatencySpikeJSON {
+	return LatencySpikeJSON{
+		AtSec:          ls.AtSec,
+		DurationSec:    ls.DurationSec,
+		ExtraLatencyMs: ls.ExtraLatencyMs,
+		Dimension:      ls.Dimension.String(),
+	}
+}
+
+func LatencySpikeFromJSON(lsj LatencySpikeJSON) simulation.LatencySpike {
+	var dimension simulation.SpikeDimension
+	switch lsj.Dimension {
+	case "request":
+		dimension = simulation.SpikeRequest
+	case "response":
+		dimension = simulation.SpikeResponse
+	default:
+		dimension = simulation.SpikeAll // fallback
+	}
+	return simulation.LatencySpike{
+		AtSec:          lsj.AtSec,
+		DurationSec:    lsj.DurationSec,
+		ExtraLatencyMs: lsj.ExtraLatencyMs,
+		Dimension:      dimension,
 	}
 }
 
@@ -183,18 +778,53 @@ func ServerBehaviorToJSON(sb simulation.ServerBehavior) ServerBehaviorJSON {
 		Errors:                   errors,
 		EnableResourceManagement: sb.EnableResourceManagement,
 		Resources: ServerResourcesJSON{
-			MaxConcurrentRequests:  sb.ResourceSettings.MaxConcurrentRequests,
-			MaxMemoryMB:            sb.ResourceSettings.MaxMemoryMB,
-			MaxQueueSize:           sb.ResourceSettings.MaxQueueSize,
-			MemoryLeakRateMBPerSec: sb.ResourceSettings.MemoryLeakRateMBPerSec,
-			MemoryPerRequestMB:     sb.ResourceSettings.MemoryPerRequestMB,
-			GCPauseIntervalSec:     sb.ResourceSettings.GCPauseIntervalSec,
-			GCPauseDurationMs:      sb.ResourceSettings.GCPauseDurationMs,
+			MaxConcurrentRequests:       sb.ResourceSettings.MaxConcurrentRequests,
+			MaxMemoryMB:                 sb.ResourceSettings.MaxMemoryMB,
+			MaxQueueSize:                sb.ResourceSettings.MaxQueueSize,
+			MemoryLeakRateMBPerSec:      sb.ResourceSettings.MemoryLeakRateMBPerSec,
+			MemoryPerRequestMB:          sb.ResourceSettings.MemoryPerRequestMB,
+			MemoryPerResponseKB:         sb.ResourceSettings.MemoryPerResponseKB,
+			GCPauseIntervalSec:          sb.ResourceSettings.GCPauseIntervalSec,
+			GCPauseDurationMs:           sb.ResourceSettings.GCPauseDurationMs,
+			QueueDepthLatencyFactor:     sb.ResourceSettings.QueueDepthLatencyFactor,
+			IOBoundFraction:             sb.ResourceSettings.IOBoundFraction,
+			MaxConcurrentIO:             sb.ResourceSettings.MaxConcurrentIO,
+			MaxDispatchQueueSize:        sb.ResourceSettings.MaxDispatchQueueSize,
+			SwapThreshold:               sb.ResourceSettings.SwapThreshold,
+			SwapLatencyMultiplier:       sb.ResourceSettings.SwapLatencyMultiplier,
+			CPUImpactWeight:             sb.ResourceSettings.CPUImpactWeight,
+			MemoryImpactWeight:          sb.ResourceSettings.MemoryImpactWeight,
+			ThreadsImpactWeight:         sb.ResourceSettings.ThreadsImpactWeight,
+			AdditiveResourceImpact:      sb.ResourceSettings.AdditiveResourceImpact,
+			MaxResourceImpactMultiplier: sb.ResourceSettings.MaxResourceImpactMultiplier,
 		},
+		CapacityRPS:            sb.CapacityRPS,
+		IdempotencyWindowMs:    sb.IdempotencyWindowMs,
+		CapacityWarmupSec:      sb.CapacityWarmupSec,
+		CapacityWarmupFraction: sb.CapacityWarmupFraction,
+		ResponseSizeBytes:      sb.ResponseSizeBytes,
+		GroupOverrides:         GenericMapValues(sb.GroupOverrides, GroupOverrideToJSON),
+		FanOutBackends:         sb.FanOutBackends,
+		FanOutBackendErrorRate: sb.FanOutBackendErrorRate,
+		FanOutQuorum:           sb.FanOutQuorum,
+		LatencyCorrelation:     sb.LatencyCorrelation,
+		SerialFraction:         sb.SerialFraction,
+		Script:                 sb.Script,
+		DeprioritizeRetries:    sb.DeprioritizeRetries,
+		TruncateToRange:        sb.TruncateToRange,
+		SingleFlight:           sb.SingleFlight,
+		LatencySpikes:          GenericMap(sb.LatencySpikes, LatencySpikeToJSON),
+
+		ResponseTemplate:        sb.ResponseTemplate,
+		ResponseTemplatesByKind: sb.ResponseTemplatesByKind,
 	}
 }
 
 func ServerBehaviorFromJSON(sbj ServerBehaviorJSON) simulation.ServerBehavior {
+	capacityWarmupFraction := sbj.CapacityWarmupFraction
+	if sbj.CapacityWarmupSec > 0 && capacityWarmupFraction == 0 {
+		capacityWarmupFraction = defaultCapacityWarmupFraction
+	}
 	responseTimeMin := GenericMap(sbj.ReponseTimeMin, BehaviorPointFromJSON)
 	responseTimeMax := GenericMap(sbj.ReponseTimeMax, BehaviorPointFromJSON)
 	errors := GenericMap(sbj.Errors, BehaviorPointFromJSON)
@@ -207,17 +837,208 @@ func ServerBehaviorFromJSON(sbj ServerBehaviorJSON) simulation.ServerBehavior {
 		Errors:                   errors,
 		EnableResourceManagement: sbj.EnableResourceManagement,
 		ResourceSettings: simulation.ResourceSettings{
-			MaxConcurrentRequests:  sbj.Resources.MaxConcurrentRequests,
-			MaxMemoryMB:            sbj.Resources.MaxMemoryMB,
-			MaxQueueSize:           sbj.Resources.MaxQueueSize,
-			MemoryLeakRateMBPerSec: sbj.Resources.MemoryLeakRateMBPerSec,
-			MemoryPerRequestMB:     sbj.Resources.MemoryPerRequestMB,
-			GCPauseIntervalSec:     sbj.Resources.GCPauseIntervalSec,
-			GCPauseDurationMs:      sbj.Resources.GCPauseDurationMs,
+			MaxConcurrentRequests:       sbj.Resources.MaxConcurrentRequests,
+			MaxMemoryMB:                 sbj.Resources.MaxMemoryMB,
+			MaxQueueSize:                sbj.Resources.MaxQueueSize,
+			MemoryLeakRateMBPerSec:      sbj.Resources.MemoryLeakRateMBPerSec,
+			MemoryPerRequestMB:          sbj.Resources.MemoryPerRequestMB,
+			MemoryPerResponseKB:         sbj.Resources.MemoryPerResponseKB,
+			GCPauseIntervalSec:          sbj.Resources.GCPauseIntervalSec,
+			GCPauseDurationMs:           sbj.Resources.GCPauseDurationMs,
+			QueueDepthLatencyFactor:     sbj.Resources.QueueDepthLatencyFactor,
+			IOBoundFraction:             sbj.Resources.IOBoundFraction,
+			MaxConcurrentIO:             sbj.Resources.MaxConcurrentIO,
+			MaxDispatchQueueSize:        sbj.Resources.MaxDispatchQueueSize,
+			SwapThreshold:               sbj.Resources.SwapThreshold,
+			SwapLatencyMultiplier:       sbj.Resources.SwapLatencyMultiplier,
+			CPUImpactWeight:             sbj.Resources.CPUImpactWeight,
+			MemoryImpactWeight:          sbj.Resources.MemoryImpactWeight,
+			ThreadsImpactWeight:         sbj.Resources.ThreadsImpactWeight,
+			AdditiveResourceImpact:      sbj.Resources.AdditiveResourceImpact,
+			MaxResourceImpactMultiplier: sbj.Resources.MaxResourceImpactMultiplier,
+		},
+		CapacityRPS:            sbj.CapacityRPS,
+		IdempotencyWindowMs:    sbj.IdempotencyWindowMs,
+		CapacityWarmupSec:      sbj.CapacityWarmupSec,
+		CapacityWarmupFraction: capacityWarmupFraction,
+		ResponseSizeBytes:      sbj.ResponseSizeBytes,
+		GroupOverrides:         GenericMapValues(sbj.GroupOverrides, GroupOverrideFromJSON),
+		FanOutBackends:         sbj.FanOutBackends,
+		FanOutBackendErrorRate: sbj.FanOutBackendErrorRate,
+		FanOutQuorum:           sbj.FanOutQuorum,
+		LatencyCorrelation:     sbj.LatencyCorrelation,
+		SerialFraction:         sbj.SerialFraction,
+		Script:                 sbj.Script,
+		DeprioritizeRetries:    sbj.DeprioritizeRetries,
+		TruncateToRange:        sbj.TruncateToRange,
+		SingleFlight:           sbj.SingleFlight,
+		LatencySpikes:          GenericMap(sbj.LatencySpikes, LatencySpikeFromJSON),
+
+		ResponseTemplate:        sbj.ResponseTemplate,
+		ResponseTemplatesByKind: sbj.ResponseTemplatesByKind,
+	}
+}
+
+func GroupOverrideToJSON(gro simulation.GroupOverride) GroupOverrideJSON {
+	return GroupOverrideJSON{
+		ResponseTimeMultiplier: &gro.ResponseTimeMultiplier,
+		ErrorRateMultiplier:    &gro.ErrorRateMultiplier,
+	}
+}
+
+func GroupOverrideFromJSON(groj GroupOverrideJSON) simulation.GroupOverride {
+	override := simulation.GroupOverride{
+		ResponseTimeMultiplier: 1,
+		ErrorRateMultiplier:    1,
+	}
+	if groj.ResponseTimeMultiplier != nil {
+		override.ResponseTimeMultiplier = *groj.ResponseTimeMultiplier
+	}
+	if groj.ErrorRateMultiplier != nil {
+		override.ErrorRateMultiplier = *groj.ErrorRateMultiplier
+	}
+	return override
+}
+
+func ProbeResultToJSON(pr simulation.ProbeResult) ProbeResultJSON {
+	errorMessage := pr.Response.Error
+	if pr.Error != nil {
+		errorMessage = pr.Error.Error()
+	}
+	return ProbeResultJSON{
+		RequestId:         pr.Request.Id,
+		TraceId:           pr.Request.TraceId,
+		Ok:                pr.Error == nil && pr.Response.Ok,
+		Outcome:           pr.Response.Outcome.String(),
+		Data:              pr.Response.Data,
+		Error:             errorMessage,
+		RetryAfterMs:      pr.Response.RetryAfterMs,
+		ConnectionSetupMs: DurationToMs(pr.Trace.ConnectionSetup),
+		RequestLatencyMs:  DurationToMs(pr.Trace.RequestLatency),
+		ProcessingTimeMs:  DurationToMs(pr.Trace.ProcessingTime),
+		ResponseLatencyMs: DurationToMs(pr.Trace.ResponseLatency),
+		ReorderDelayMs:    DurationToMs(pr.Trace.ReorderDelay),
+		TotalTimeMs:       DurationToMs(pr.TotalTime),
+	}
+}
+
+func BehaviorEvalFromJSON(bej BehaviorEvalJSON) simulation.HookEval {
+	eval := simulation.HookEval{
+		Script: bej.Script,
+		Hook:   bej.Hook,
+		Request: simulation.Request{
+			Id:             bej.Request.Id,
+			ClientId:       bej.Request.ClientId,
+			Group:          bej.Request.Group,
+			Data:           bej.Request.Data,
+			IdempotencyKey: bej.Request.IdempotencyKey,
+			TraceId:        bej.Request.TraceId,
+			Kind:           bej.Request.Kind,
 		},
+		Meta:  bej.Request.Meta,
+		Error: bej.Error,
+	}
+	if resp := bej.Response; resp != nil {
+		eval.Response = &simulation.Response{
+			Id:           bej.Request.Id,
+			TraceId:      resp.TraceId,
+			Outcome:      simulation.ParseOutcome(resp.Outcome),
+			Ok:           resp.Ok,
+			Data:         resp.Data,
+			Error:        resp.Error,
+			RetryAfterMs: resp.RetryAfterMs,
+		}
+	}
+	return eval
+}
+
+func InvariantViolationToJSON(iv simulation.InvariantViolation) InvariantViolationJSON {
+	return InvariantViolationJSON{
+		Invariant: iv.Invariant,
+		Values:    iv.Values,
+	}
+}
+
+func ActiveBehaviorToJSON(ab simulation.ActiveBehavior) ActiveBehaviorJSON {
+	return ActiveBehaviorJSON{
+		Group:         ab.Group,
+		Source:        ab.Source,
+		Hash:          ab.Hash,
+		Compiled:      ab.Compiled,
+		Error:         ab.Error,
+		Clients:       ab.Clients,
+		ConfigChanged: ab.ConfigChanged,
 	}
 }
 
+func BehaviorEvalResultToJSON(r simulation.HookEvalResult) BehaviorEvalResultJSON {
+	output := r.Output
+	if output == nil {
+		output = []string{}
+	}
+	return BehaviorEvalResultJSON{
+		Allow:          r.Allow,
+		DelayMs:        r.DelayMs,
+		TimeoutMs:      r.TimeoutMs,
+		Meta:           r.Meta,
+		IdempotencyKey: r.IdempotencyKey,
+		TraceId:        r.TraceId,
+		Output:         output,
+		Error:          r.Error,
+	}
+}
+
+func RequestTimelineToJSON(rt simulation.RequestTimeline) RequestTimelineJSON {
+	return RequestTimelineJSON{
+		Requests: GenericMap(rt.Requests, func(r simulation.TimelineRequest) TimelineRequestJSON {
+			return TimelineRequestJSON{
+				OffsetMs:  DurationToMs(r.Offset),
+				ClientId:  r.ClientId,
+				Group:     r.Group,
+				TraceId:   r.TraceId,
+				SizeBytes: r.SizeBytes,
+				Kind:      r.Kind,
+			}
+		}),
+		Truncated: rt.Truncated,
+	}
+}
+
+func RequestTimelineFromJSON(rtj RequestTimelineJSON) simulation.RequestTimeline {
+	return simulation.RequestTimeline{
+		Requests: GenericMap(rtj.Requests, func(r TimelineRequestJSON) simulation.TimelineRequest {
+			return simulation.TimelineRequest{
+				Offset:    time.Duration(r.OffsetMs * float64(time.Millisecond)),
+				ClientId:  r.ClientId,
+				Group:     r.Group,
+				TraceId:   r.TraceId,
+				SizeBytes: r.SizeBytes,
+				Kind:      r.Kind,
+			}
+		}),
+		Truncated: rtj.Truncated,
+	}
+}
+
+func TimeSeriesPointToJSON(p simulation.TimeSeriesPoint) TimeSeriesPointJSON {
+	return TimeSeriesPointJSON{
+		Timestamp:         p.Timestamp.UnixMilli(),
+		ElapsedMs:         p.Elapsed.Milliseconds(),
+		RPS:               p.RPS,
+		ErrorRate:         p.ErrorRate,
+		P50ResponseTimeMs: DurationToMs(p.P50ResponseTime),
+		P95ResponseTimeMs: DurationToMs(p.P95ResponseTime),
+		P99ResponseTimeMs: DurationToMs(p.P99ResponseTime),
+		CPUUtilization:    p.CPUUtilization,
+		MemoryUtilization: p.MemoryUtilization,
+	}
+}
+
+// DurationToMs converts a duration to fractional milliseconds
+func DurationToMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 // GenericMap takes a slice of type S and a function that transforms S to D,
 // returning a new slice of type D.
 func GenericMap[S, D any](slice []S, fn func(S) D) []D {
@@ -233,6 +1054,19 @@ func GenericMap[S, D any](slice []S, fn func(S) D) []D {
 	return result
 }
 
+func GenericMapValues[K comparable, S, D any](m map[K]S, fn func(S) D) map[K]D {
+	if m == nil {
+		return nil
+	}
+
+	result := make(map[K]D, len(m))
+	for k, v := range m {
+		result[k] = fn(v)
+	}
+
+	return result
+}
+
 // func ServerResourceMetricsToJSON(sr simulation.ResourceState) ServerResourceMetricsJSON {
 // 	return ServerResourceMetricsJSON{
 // 		CPUUtilization:     sr.CPUUtilization,
@@ -1,9 +1,15 @@
 package web
 
 import (
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -24,7 +30,13 @@ func SimulationHandler(d *Dashboard) http.HandlerFunc {
 		// Reset (or Create) Simulation
 		if r.Method == "POST" {
 			log.Println("[POST /api/simulation] Resetting simulation")
-			d.ResetSimulation()
+			err := d.ResetSimulation()
+			if err != nil {
+				// New simulation is created anyway, but previous one leaked goroutines
+				log.Printf("[POST /api/simulation] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -57,20 +69,141 @@ func SimulationHandler(d *Dashboard) http.HandlerFunc {
 				}
 			}
 
-			d.StartSimulation(limitSeconds)
+			started, err := d.StartSimulation(limitSeconds)
+			if err != nil {
+				log.Printf("[PUT /api/simulation] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if !started {
+				http.Error(w, "Simulation is already running", http.StatusConflict)
+				return
+			}
+
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
-		// DELETE /api/simulation
-		// Stop Simulation
+		// DELETE /api/simulation?drainMs=<ms>
+		// Stop Simulation, with drainMs clients stop sending new requests and in-flight ones complete for up to drainMs first
 		if r.Method == "DELETE" {
+			if drain := r.URL.Query().Get("drainMs"); drain != "" {
+				drainMs, err := strconv.Atoi(drain)
+				if err != nil || drainMs <= 0 {
+					http.Error(w, "Invalid drainMs", http.StatusBadRequest)
+					return
+				}
+				log.Printf("[DELETE /api/simulation] Draining simulation for up to %dms", drainMs)
+				d.DrainSimulation(time.Duration(drainMs) * time.Millisecond)
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+
 			log.Println("[DELETE /api/simulation] Stopping simulation")
 			d.StopSimulation()
 			w.WriteHeader(http.StatusOK)
 			return
 		}
 
+		// PATCH /api/simulation
+		// Pause or resume request generation of running Simulation
+		if r.Method == "PATCH" {
+			var body struct {
+				Action string `json:"action"` // pause | resume
+			}
+			err := json.NewDecoder(r.Body).Decode(&body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			switch body.Action {
+			case "pause":
+				log.Println("[PATCH /api/simulation] Pausing simulation")
+				err = d.PauseSimulation()
+			case "resume":
+				log.Println("[PATCH /api/simulation] Resuming simulation")
+				err = d.ResumeSimulation()
+			default:
+				http.Error(w, "Invalid action", http.StatusBadRequest)
+				return
+			}
+			if err != nil {
+				log.Printf("[PATCH /api/simulation] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// SimulationPrepareHandler handles preparing clients of the next run (warm pool)
+func SimulationPrepareHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// POST /api/simulation/prepare
+		// Create clients beforehand, next start fires them at once, without ramp-up
+		if r.Method == "POST" {
+			log.Println("[POST /api/simulation/prepare] Preparing simulation")
+			err := d.PrepareSimulation()
+			if err != nil {
+				log.Printf("[POST /api/simulation/prepare] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// SimulationSettingsHandler handles getting and setting simulation-wide settings
+func SimulationSettingsHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/simulation/settings
+		// Get simulation settings
+		if r.Method == "GET" {
+			settings, err := d.GetSimulationSettings()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(settings)
+			return
+		}
+
+		// PUT /api/simulation/settings
+		// Update simulation settings (omitted fields keep their current values)
+		if r.Method == "PUT" {
+			settings, err := d.GetSimulationSettings()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			err = json.NewDecoder(r.Body).Decode(&settings)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			err = d.SetSimulationSettings(settings)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
@@ -193,7 +326,7 @@ func ServerBehaviorHandler(d *Dashboard) http.HandlerFunc {
 		}
 
 		// PUT /api/server
-		// Update server behavior
+		// Update server behavior (with optional transition time in ms)
 		if r.Method == "PUT" {
 			var behaviorDTO ServerBehaviorJSON
 			err := json.NewDecoder(r.Body).Decode(&behaviorDTO)
@@ -202,7 +335,21 @@ func ServerBehaviorHandler(d *Dashboard) http.HandlerFunc {
 				return
 			}
 
-			err = d.SetServerBehavior(behaviorDTO)
+			transitionMs := 0
+			transitionStr := r.URL.Query().Get("transition")
+			if transitionStr != "" {
+				transitionMs, err = strconv.Atoi(transitionStr)
+				if err != nil || transitionMs < 0 {
+					http.Error(w, "Invalid transition", http.StatusBadRequest)
+					return
+				}
+			}
+
+			if transitionMs > 0 {
+				err = d.TransitionServerBehavior(behaviorDTO, time.Duration(transitionMs)*time.Millisecond)
+			} else {
+				err = d.SetServerBehavior(behaviorDTO)
+			}
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -256,6 +403,485 @@ func NetworkBehaviorHandler(d *Dashboard) http.HandlerFunc {
 	}
 }
 
+// ProbeHandler handles sending a single synthetic request through the simulation
+func ProbeHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// POST /api/probe
+		// Send single synthetic request (with optional timeout in ms)
+		if r.Method == "POST" {
+			log.Println("[POST /api/probe] Sending probe request")
+
+			// Probe is not tied to the request, so that client disconnect does not cancel it mid-flight
+			ctx := context.Background()
+			timeoutStr := r.URL.Query().Get("timeout")
+			if timeoutStr != "" {
+				if v, err := strconv.Atoi(timeoutStr); err == nil && v > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, time.Duration(v)*time.Millisecond)
+					defer cancel()
+				}
+			}
+
+			result, err := d.Probe(ctx)
+			if err != nil {
+				log.Printf("[POST /api/probe] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// BehaviorEvalHandler handles evaluating a single behavior hook against synthetic request
+func BehaviorEvalHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// POST /api/behaviors/eval
+		// Compile behavior script and run one hook, returning its result and print output
+		if r.Method == "POST" {
+			var evalDTO BehaviorEvalJSON
+			if err := json.NewDecoder(r.Body).Decode(&evalDTO); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("[POST /api/behaviors/eval] Evaluating hook %s", evalDTO.Hook)
+
+			result, err := d.EvaluateBehavior(evalDTO)
+			if err != nil {
+				log.Printf("[POST /api/behaviors/eval] Error: %v", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ConfigHandler handles saving and loading the whole simulation configuration
+func ConfigHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/config
+		// Get client configs, server and network behaviors as a single scenario
+		if r.Method == "GET" {
+			config, err := d.ExportConfig()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(config)
+			return
+		}
+
+		// PUT /api/config
+		// Replace client configs, server and network behaviors with the given scenario (not while running)
+		if r.Method == "PUT" {
+			var configDTO SimulationConfigJSON
+			err := json.NewDecoder(r.Body).Decode(&configDTO)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			err = d.ImportConfig(configDTO)
+			if err != nil {
+				log.Printf("[PUT /api/config] Error importing configuration: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ActiveBehaviorsHandler handles getting behavior scripts in effect for live clients
+func ActiveBehaviorsHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/behaviors/active
+		// Get behavior script of live clients per group, and whether it compiled or clients fell back to noop behavior
+		if r.Method == "GET" {
+			active, err := d.GetActiveBehaviors()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(active)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricsRecordingHandler handles getting and toggling metrics recording
+func MetricsRecordingHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/metrics/recording
+		// Get whether metrics are being recorded
+		if r.Method == "GET" {
+			recording, err := d.GetMetricsRecording()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(recording)
+			return
+		}
+
+		// PUT /api/metrics/recording
+		// Pause or resume metrics recording, simulation keeps running
+		if r.Method == "PUT" {
+			var recordingDTO MetricsRecordingJSON
+			err := json.NewDecoder(r.Body).Decode(&recordingDTO)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			err = d.SetMetricsRecording(recordingDTO)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// maxCurveResolution bounds the number of points per curve returned by curves endpoint
+const maxCurveResolution = 10000
+
+// CurvesHandler handles exporting evaluated server and network behavior curves
+func CurvesHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/curves?resolution=500
+		// Get each behavior curve sampled at resolution points across its time range
+		if r.Method == "GET" {
+			resolution := 500
+			if resolutionStr := r.URL.Query().Get("resolution"); resolutionStr != "" {
+				v, err := strconv.Atoi(resolutionStr)
+				if err != nil || v < 2 || v > maxCurveResolution {
+					http.Error(w, fmt.Sprintf("Invalid resolution, must be between 2 and %d", maxCurveResolution), http.StatusBadRequest)
+					return
+				}
+				resolution = v
+			}
+
+			curves, err := d.GetCurves(resolution)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(curves)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// ValidateHandler handles pre-flight check of the simulation configuration
+func ValidateHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/validate
+		// Get consolidated list of configuration warnings and errors
+		if r.Method == "GET" {
+			result, err := d.Validate()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(result)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricsMarkHandler handles storing named metrics baselines
+func MetricsMarkHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// POST /api/metrics/mark
+		// Capture current metrics counters as a named baseline
+		if r.Method == "POST" {
+			var markDTO MetricsMarkJSON
+			err := json.NewDecoder(r.Body).Decode(&markDTO)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if markDTO.Name == "" {
+				http.Error(w, "Baseline name is required", http.StatusBadRequest)
+				return
+			}
+
+			err = d.MarkMetrics(markDTO)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricsDiffHandler handles getting metrics change since a named baseline
+func MetricsDiffHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/metrics/diff?from=<name>
+		// Get counters change since the baseline, and current response time percentiles
+		if r.Method == "GET" {
+			from := r.URL.Query().Get("from")
+			if from == "" {
+				http.Error(w, "Baseline name is required", http.StatusBadRequest)
+				return
+			}
+
+			diff, err := d.GetMetricsDiff(from)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(diff)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricsInvariantsHandler handles checking consistency of metrics counters
+func MetricsInvariantsHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/metrics/invariants
+		// Get relationships between counters which do not hold, like more server responses than received requests
+		if r.Method == "GET" {
+			invariants, err := d.CheckMetricsInvariants()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(invariants)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// PrometheusMetricsHandler handles scraping of the current metrics
+func PrometheusMetricsHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/metrics/prometheus
+		// Get the latest broadcast metrics snapshot in Prometheus text exposition format
+		if r.Method == "GET" {
+			snapshot, err := d.GetLatestMetricsSnapshot()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+			if err := writePrometheusMetrics(w, snapshot); err != nil {
+				log.Printf("[GET /api/metrics/prometheus] Error: %v", err)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// MetricsHistoryHandler handles downloading metrics snapshots of the latest run
+func MetricsHistoryHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/metrics/history?runId=<simulation id>
+		// Get metrics snapshots broadcast during the latest run, as JSON or as CSV with `Accept: text/csv`
+		if r.Method == "GET" {
+			history, err := d.GetMetricsHistory(r.URL.Query().Get("runId"))
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+
+			if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+				w.Header().Set("Content-Type", "text/csv")
+				w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", history.RunId+".csv"))
+				if err := writeMetricsHistoryCSV(w, history); err != nil {
+					log.Printf("[GET /api/metrics/history] Error: %v", err)
+				}
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(history)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// writeMetricsHistoryCSV writes one row per snapshot, with a column per scalar metric (nested maps are skipped),
+// timestamp goes first and the rest of columns are sorted by name
+func writeMetricsHistoryCSV(w io.Writer, history MetricsHistoryJSON) error {
+	columnSet := make(map[string]bool)
+	for _, snapshot := range history.Snapshots {
+		for key, value := range snapshot {
+			switch value.(type) {
+			case int, int64, float64, bool, string, nil:
+				columnSet[key] = true
+			}
+		}
+	}
+	delete(columnSet, "timestamp")
+	columns := append([]string{"timestamp"}, slices.Sorted(maps.Keys(columnSet))...)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	row := make([]string, len(columns))
+	for _, snapshot := range history.Snapshots {
+		for i, column := range columns {
+			row[i] = ""
+			if value, ok := snapshot[column]; ok && value != nil {
+				row[i] = fmt.Sprint(value)
+			}
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// TimeSeriesHandler handles getting per-second metrics rollup of the run
+func TimeSeriesHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/timeseries
+		// Get one summary row per second of the current (or last) run
+		if r.Method == "GET" {
+			points, err := d.GetTimeSeries()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(points)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// TimelineHandler handles getting captured request arrivals
+func TimelineHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/timeline
+		// Get request arrivals captured from the current (or last) run
+		if r.Method == "GET" {
+			timeline, err := d.GetTimeline()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(timeline)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// TimelineReplayHandler handles getting, setting and clearing the timeline replayed by clients
+func TimelineReplayHandler(d *Dashboard) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// GET /api/timeline/replay
+		// Get the timeline replayed by clients (null if clients send at request rate)
+		if r.Method == "GET" {
+			timeline, err := d.GetReplayTimeline()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(timeline)
+			return
+		}
+
+		// PUT /api/timeline/replay
+		// Replay the given timeline (as captured by GET /api/timeline) in the next runs
+		if r.Method == "PUT" {
+			var timeline RequestTimelineJSON
+			if err := json.NewDecoder(r.Body).Decode(&timeline); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			log.Printf("[PUT /api/timeline/replay] Replaying %d requests", len(timeline.Requests))
+
+			err := d.SetReplayTimeline(&timeline)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		// DELETE /api/timeline/replay
+		// Stop replaying, clients send at request rate again
+		if r.Method == "DELETE" {
+			log.Println("[DELETE /api/timeline/replay] Clearing replay timeline")
+
+			err := d.SetReplayTimeline(nil)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
 // WebSocketMetricsHandler handles WebSocket connections for streaming metrics
 func WebSocketMetricsHandler(d *Dashboard, ws *WebSocketHub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
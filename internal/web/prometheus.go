@@ -0,0 +1,222 @@
+package web
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// prometheusPrefix is prepended to names of all exposed metrics
+const prometheusPrefix = "sim_"
+
+// prometheusMetric describes how a metrics snapshot value is exposed in Prometheus text format,
+// metrics with the same name must go one after another, they are rendered as one family
+type prometheusMetric struct {
+	key        string   // Snapshot key
+	name       string   // Metric name, without prefix
+	kind       string   // counter | gauge
+	help       string   // Help text, only the first metric of a family needs it
+	labels     string   // Constant labels, like `quantile="0.5"`
+	labelNames []string // Label names for values which are maps, one per nesting level
+	divisor    float64  // Divides value to convert it to base units, like milliseconds to seconds (0 keeps value as is)
+}
+
+const msPerSecond = 1000
+
+// labelValueEscaper escapes label values as required by the text format
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// prometheusMetrics lists exposed snapshot values, each explicitly classified as counter or gauge
+var prometheusMetrics = []prometheusMetric{
+	// Client-side metrics
+	{key: "client_blocked_req", name: "client_blocked_requests_total", kind: "counter", help: "Requests blocked by clients' behavior"},
+	{key: "client_sent_req", name: "client_sent_requests_total", kind: "counter", help: "Requests sent by clients, including retries"},
+	{key: "client_retry_req", name: "client_retried_requests_total", kind: "counter", help: "Requests retried by clients"},
+	{key: "client_success_resp", name: "client_success_responses_total", kind: "counter", help: "Successful responses received by clients"},
+	{key: "client_error_resp", name: "client_error_responses_total", kind: "counter", help: "Failed responses received by clients"},
+	{key: "client_timeout_req", name: "client_timeout_requests_total", kind: "counter", help: "Requests clients gave up waiting for after request timeout"},
+	{key: "client_abandoned", name: "client_abandoned_total", kind: "counter", help: "Clients which abandoned their session after consecutive failed requests"},
+	{key: "client_good_resp", name: "client_good_responses_total", kind: "counter", help: "Successful responses received by clients before goodput deadline"},
+	{key: "client_resp_by_outcome", name: "client_responses_total", kind: "counter", help: "Request attempts seen by clients, by outcome", labelNames: []string{"outcome"}},
+	{key: "client_resp_by_kind", name: "client_responses_by_kind_total", kind: "counter", help: "Request attempts seen by clients, by request kind and outcome", labelNames: []string{"kind", "outcome"}},
+	{key: "attempts_histogram", name: "client_completed_requests_total", kind: "counter", help: "Completed requests, by number of attempts made", labelNames: []string{"attempts"}},
+	{key: "active_clients", name: "active_clients", kind: "gauge", help: "Active clients, by group", labelNames: []string{"group"}},
+	{key: "active_clients_total", name: "all_active_clients", kind: "gauge", help: "Active clients of all groups"},
+	{key: "target_clients_total", name: "target_clients", kind: "gauge", help: "Configured clients of all groups"},
+	{key: "ramp_up_pct", name: "ramp_up_percent", kind: "gauge", help: "Elapsed share of the ramp-up, in percent"},
+	{key: "slo_resp", name: "client_slo_responses_total", kind: "counter", help: "Responses checked against the SLO latency"},
+	{key: "slo_violations", name: "client_slo_violations_total", kind: "counter", help: "Responses slower than the SLO latency"},
+	{key: "slo_violation_rate", name: "client_slo_violation_ratio", kind: "gauge", help: "Share of responses slower than the SLO latency"},
+	{key: "apdex", name: "client_apdex_score", kind: "gauge", help: "Apdex score of response times over the metrics window"},
+	{key: "client_starved", name: "client_starved_clients", kind: "gauge", help: "Active clients which have not sent a request for longer than starvation threshold"},
+
+	// Throughput and goodput metrics (sliding window)
+	{key: "client_sent_rps", name: "client_sent_rps", kind: "gauge", help: "Requests sent by clients in the last second"},
+	{key: "client_success_rps", name: "client_success_rps", kind: "gauge", help: "Successful responses received by clients in the last second"},
+	{key: "server_received_rps", name: "server_received_rps", kind: "gauge", help: "Requests received by server in the last second"},
+	{key: "server_success_rps", name: "server_success_rps", kind: "gauge", help: "Successful responses returned by server in the last second"},
+	{key: "goodput_rps", name: "goodput_rps", kind: "gauge", help: "Useful responses received by clients in the last second"},
+
+	// Network metrics
+	{key: "network_failed_reqs", name: "network_failed_requests_total", kind: "counter", help: "Requests failed due to network errors"},
+	{key: "network_black_holed_req", name: "network_black_holed_requests_total", kind: "counter", help: "Requests swallowed by the network without any response"},
+	{key: "network_new_conn", name: "network_new_connections_total", kind: "counter", help: "Connections established by clients"},
+	{key: "network_reordered_resp", name: "network_reordered_responses_total", kind: "counter", help: "Responses delivered out of order"},
+	{key: "network_in_flight", name: "network_in_flight_trips", kind: "gauge", help: "One-way trips currently in flight on the network"},
+	{key: "network_peak_in_flight", name: "network_peak_in_flight_trips", kind: "gauge", help: "Highest number of one-way trips simultaneously in flight"},
+
+	// Server-side metrics
+	{key: "server_received_req", name: "server_received_requests_total", kind: "counter", help: "Requests received by server"},
+	{key: "server_success_resp", name: "server_success_responses_total", kind: "counter", help: "Successful responses returned by server"},
+	{key: "server_error_resp", name: "server_error_responses_total", kind: "counter", help: "Failed responses returned by server"},
+	{key: "server_dedup_req", name: "server_deduplicated_requests_total", kind: "counter", help: "Requests answered with the original response for a repeated idempotency key"},
+	{key: "server_coalesced_req", name: "server_coalesced_requests_total", kind: "counter", help: "Requests which shared result of concurrent processing of the same key"},
+	{key: "server_queue_rejected_req", name: "server_queue_rejected_requests_total", kind: "counter", help: "Requests rejected because accept queue was full"},
+	{key: "server_dispatch_rejected_req", name: "server_dispatch_rejected_requests_total", kind: "counter", help: "Accepted requests rejected because dispatch queue was full"},
+	{key: "server_retry_shed_req", name: "server_shed_retries_total", kind: "counter", help: "Retries rejected early, because server deprioritizes retries"},
+	{key: "server_fanout_partial_req", name: "server_fanout_partial_requests_total", kind: "counter", help: "Requests succeeded by quorum, with some of the backends failed"},
+	{key: "server_fanout_failed_req", name: "server_fanout_failed_requests_total", kind: "counter", help: "Requests failed because fan-out quorum was not reached"},
+
+	// Server resources
+	{key: "server_cpu_utilization", name: "server_cpu_utilization", kind: "gauge", help: "Server CPU utilization ratio"},
+	{key: "server_memory_utilization", name: "server_memory_utilization", kind: "gauge", help: "Server memory utilization ratio"},
+	{key: "server_threads_utilization", name: "server_threads_utilization", kind: "gauge", help: "Server workers utilization ratio"},
+	{key: "server_queue_utilization", name: "server_queue_utilization", kind: "gauge", help: "Server accept queue utilization ratio"},
+	{key: "server_io_utilization", name: "server_io_utilization", kind: "gauge", help: "Server IO pool utilization ratio"},
+	{key: "server_dispatch_queue_utilization", name: "server_dispatch_queue_utilization", kind: "gauge", help: "Server dispatch queue utilization ratio"},
+	{key: "server_active_requests", name: "server_active_requests", kind: "gauge", help: "Requests being processed by server workers"},
+	{key: "server_queued_requests", name: "server_queued_requests", kind: "gauge", help: "Requests waiting in server accept queue"},
+	{key: "server_dispatch_queued_requests", name: "server_dispatch_queued_requests", kind: "gauge", help: "Requests waiting in server dispatch queue"},
+	{key: "server_active_io", name: "server_active_io", kind: "gauge", help: "IO-bound requests holding an IO slot"},
+	{key: "server_response_bytes_in_flight", name: "server_response_in_flight_bytes", kind: "gauge", help: "Bytes of responses buffered until delivered to clients"},
+	{key: "server_avg_queue_time_ms", name: "server_avg_queue_time_seconds", kind: "gauge", help: "Average time requests wait in server queue", divisor: msPerSecond},
+	{key: "server_max_queue_time_ms", name: "server_max_queue_time_seconds", kind: "gauge", help: "Maximum time requests waited in server queue", divisor: msPerSecond},
+
+	// Response time metrics (sliding window)
+	{key: "p50_response_time_ms", name: "response_time_seconds", kind: "gauge", help: "Response time percentiles seen by clients over the metrics window", labels: `quantile="0.5"`, divisor: msPerSecond},
+	{key: "p80_response_time_ms", name: "response_time_seconds", kind: "gauge", labels: `quantile="0.8"`, divisor: msPerSecond},
+	{key: "p95_response_time_ms", name: "response_time_seconds", kind: "gauge", labels: `quantile="0.95"`, divisor: msPerSecond},
+	{key: "p99_response_time_ms", name: "response_time_seconds", kind: "gauge", labels: `quantile="0.99"`, divisor: msPerSecond},
+	{key: "p999_response_time_ms", name: "response_time_seconds", kind: "gauge", labels: `quantile="0.999"`, divisor: msPerSecond},
+	{key: "min_response_time_ms", name: "response_time_min_seconds", kind: "gauge", help: "Minimum response time over the metrics window", divisor: msPerSecond},
+	{key: "max_response_time_ms", name: "response_time_max_seconds", kind: "gauge", help: "Maximum response time over the metrics window", divisor: msPerSecond},
+	{key: "avg_response_time_ms", name: "response_time_avg_seconds", kind: "gauge", help: "Average response time over the metrics window", divisor: msPerSecond},
+
+	// Network latency metrics (sliding window)
+	{key: "min_request_latency_ms", name: "request_latency_min_seconds", kind: "gauge", help: "Minimum network latency on the way to the server", divisor: msPerSecond},
+	{key: "max_request_latency_ms", name: "request_latency_max_seconds", kind: "gauge", help: "Maximum network latency on the way to the server", divisor: msPerSecond},
+	{key: "min_response_latency_ms", name: "response_latency_min_seconds", kind: "gauge", help: "Minimum network latency on the way back from the server", divisor: msPerSecond},
+	{key: "max_response_latency_ms", name: "response_latency_max_seconds", kind: "gauge", help: "Maximum network latency on the way back from the server", divisor: msPerSecond},
+
+	// Warmup metrics, not exposed until known
+	{key: "first_success_ms", name: "first_success_seconds", kind: "gauge", help: "Time from start to the first successful response", divisor: msPerSecond},
+	{key: "stabilized_ms", name: "stabilized_seconds", kind: "gauge", help: "Time from start until error rate dropped below target", divisor: msPerSecond},
+
+	{key: "recording", name: "metrics_recording", kind: "gauge", help: "Whether metrics are being recorded (1) or frozen (0)"},
+}
+
+// writePrometheusMetrics renders metrics snapshot in Prometheus text exposition format
+func writePrometheusMetrics(w io.Writer, snapshot map[string]any) error {
+	var sb strings.Builder
+	family := ""
+	for _, metric := range prometheusMetrics {
+		value, ok := snapshot[metric.key]
+		if !ok || value == nil {
+			continue
+		}
+
+		name := prometheusPrefix + metric.name
+		if name != family {
+			family = name
+			if metric.help != "" {
+				fmt.Fprintf(&sb, "# HELP %s %s\n", name, metric.help)
+			}
+			fmt.Fprintf(&sb, "# TYPE %s %s\n", name, metric.kind)
+		}
+
+		for _, sample := range prometheusSamples(value, metric.labelNames) {
+			labels := joinLabels(metric.labels, sample.labels)
+			if labels != "" {
+				labels = "{" + labels + "}"
+			}
+			v := sample.value
+			if metric.divisor != 0 {
+				v /= metric.divisor
+			}
+			fmt.Fprintf(&sb, "%s%s %s\n", name, labels, strconv.FormatFloat(v, 'g', -1, 64))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// prometheusSample is a single value of a metric with its labels
+type prometheusSample struct {
+	labels string
+	value  float64
+}
+
+// prometheusSamples flattens snapshot value into samples, maps are expanded into one sample per key
+// (with label of the nesting level), ordered by label values
+func prometheusSamples(value any, labelNames []string) []prometheusSample {
+	switch v := value.(type) {
+	case int64:
+		return []prometheusSample{{value: float64(v)}}
+	case int:
+		return []prometheusSample{{value: float64(v)}}
+	case float64:
+		return []prometheusSample{{value: v}}
+	case bool:
+		if v {
+			return []prometheusSample{{value: 1}}
+		}
+		return []prometheusSample{{value: 0}}
+	case map[string]int64:
+		return labeledSamples(v, labelNames, func(count int64) []prometheusSample {
+			return []prometheusSample{{value: float64(count)}}
+		})
+	case map[int]int64:
+		return labeledSamples(v, labelNames, func(count int64) []prometheusSample {
+			return []prometheusSample{{value: float64(count)}}
+		})
+	case map[string]map[string]int64:
+		return labeledSamples(v, labelNames, func(counts map[string]int64) []prometheusSample {
+			return prometheusSamples(counts, labelNames[1:])
+		})
+	default:
+		return nil
+	}
+}
+
+// labeledSamples expands map into samples of its values, labelled with the first label name
+func labeledSamples[K cmp.Ordered, V any](m map[K]V, labelNames []string, samples func(V) []prometheusSample) []prometheusSample {
+	if len(labelNames) == 0 {
+		return nil
+	}
+
+	var result []prometheusSample
+	for _, key := range slices.Sorted(maps.Keys(m)) {
+		label := fmt.Sprintf(`%s="%s"`, labelNames[0], labelValueEscaper.Replace(fmt.Sprint(key)))
+		for _, sample := range samples(m[key]) {
+			sample.labels = joinLabels(label, sample.labels)
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// joinLabels joins non-empty label lists with comma
+func joinLabels(labels ...string) string {
+	var nonEmpty []string
+	for _, l := range labels {
+		if l != "" {
+			nonEmpty = append(nonEmpty, l)
+		}
+	}
+	return strings.Join(nonEmpty, ",")
+}
@@ -2,15 +2,52 @@ package web
 
 import (
 	"net/http"
+	"strings"
 )
 
-// SetupRoutes initializes and registers all web routes for the simulation
-func SetupRoutes(mux *http.ServeMux, d *Dashboard) {
+// SetupRoutes initializes and registers all web routes for the simulation,
+// under the base path if it is not empty, like "/simulator" when served behind a reverse proxy
+func SetupRoutes(mux *http.ServeMux, d *Dashboard, basePath string) {
+	basePath = normalizeBasePath(basePath)
+	if basePath != "" {
+		// Handlers see paths without base path, as if routes were registered at the root
+		routes := http.NewServeMux()
+		SetupRoutes(routes, d, "")
+		mux.Handle(basePath+"/", http.StripPrefix(basePath, routes))
+		return
+	}
+
 	mux.HandleFunc("/api/simulation", SimulationHandler(d))
+	mux.HandleFunc("/api/simulation/settings", SimulationSettingsHandler(d))
+	mux.HandleFunc("/api/simulation/prepare", SimulationPrepareHandler(d))
 	mux.HandleFunc("/api/clients", ClientsHandler(d))
 	mux.HandleFunc("/api/clients/", ClientsHandler(d))
 	mux.HandleFunc("/api/server", ServerBehaviorHandler(d))
 	mux.HandleFunc("/api/network", NetworkBehaviorHandler(d))
+	mux.HandleFunc("/api/config", ConfigHandler(d))
+	mux.HandleFunc("/api/probe", ProbeHandler(d))
+	mux.HandleFunc("/api/validate", ValidateHandler(d))
+	mux.HandleFunc("/api/behaviors/eval", BehaviorEvalHandler(d))
+	mux.HandleFunc("/api/behaviors/active", ActiveBehaviorsHandler(d))
+	mux.HandleFunc("/api/curves", CurvesHandler(d))
+	mux.HandleFunc("/api/timeseries", TimeSeriesHandler(d))
+	mux.HandleFunc("/api/timeline", TimelineHandler(d))
+	mux.HandleFunc("/api/timeline/replay", TimelineReplayHandler(d))
+	mux.HandleFunc("/api/metrics/recording", MetricsRecordingHandler(d))
+	mux.HandleFunc("/api/metrics/mark", MetricsMarkHandler(d))
+	mux.HandleFunc("/api/metrics/diff", MetricsDiffHandler(d))
+	mux.HandleFunc("/api/metrics/history", MetricsHistoryHandler(d))
+	mux.HandleFunc("/api/metrics/prometheus", PrometheusMetricsHandler(d))
+	mux.HandleFunc("/api/metrics/invariants", MetricsInvariantsHandler(d))
 	mux.HandleFunc("/api/ws/metrics", WebSocketMetricsHandler(d, d.metricsWs))
 	mux.HandleFunc("/api/ws/notifications", WebSocketNotifyHandler(d, d.notifyWs))
 }
+
+// normalizeBasePath returns base path with leading slash and without trailing one, empty for the root
+func normalizeBasePath(basePath string) string {
+	basePath = strings.Trim(basePath, "/")
+	if basePath == "" {
+		return ""
+	}
+	return "/" + basePath
+}
@@ -17,6 +17,9 @@ const (
 	pingPeriod = (pongWait * 9) / 10 // Send pings to peer with this period (must be less than pongWait)
 )
 
+// maxConsecutiveDroppedFrames is the number of frames in a row dropped for a slow client before it is disconnected
+const maxConsecutiveDroppedFrames = 10
+
 // WebSocketHub maintains the set of active websocket connections and broadcasts metrics to them
 type WebSocketHub struct {
 	clients              map[*WebSocketClient]bool // Registered clients
@@ -36,6 +39,9 @@ type WebSocketClient struct {
 	registered   chan struct{}
 	unregistered chan struct{}
 	Name         string
+
+	droppedFrames            int // Total frames dropped because send buffer was full (guarded by hub mutex)
+	consecutiveDroppedFrames int // Frames dropped in a row because send buffer was full (guarded by hub mutex)
 }
 
 // Upgrader contains websocket configuration
@@ -113,11 +119,18 @@ func (h *WebSocketHub) run() {
 				select {
 				case client.sendBuffer <- message:
 					// log.Printf("WebSocketHub: Broadcasted message to client %p", client)
+					client.consecutiveDroppedFrames = 0
 				default:
-					log.Printf("WebSocketHub: Client %p (%s) buffer full during broadcast, closing connection", client, client.Name)
-					// If client's buffer is full, close the connection
-					close(client.sendBuffer)
-					delete(h.clients, client)
+					// If client's buffer is full, drop this frame, and close the connection only on sustained overflow
+					client.droppedFrames++
+					client.consecutiveDroppedFrames++
+					if client.consecutiveDroppedFrames < maxConsecutiveDroppedFrames {
+						log.Printf("WebSocketHub: Client %p (%s) buffer full during broadcast, dropped frame (%d in a row, %d total)", client, client.Name, client.consecutiveDroppedFrames, client.droppedFrames)
+					} else {
+						log.Printf("WebSocketHub: Client %p (%s) buffer full for %d broadcasts in a row, closing connection", client, client.Name, client.consecutiveDroppedFrames)
+						delete(h.clients, client)
+						go client.CloseWithReason(websocket.CloseTryAgainLater, "send buffer overflow")
+					}
 				}
 			}
 			// log.Printf("WebSocketHub: Finished broadcasting to %d clients", len(h.clients))
@@ -190,6 +203,16 @@ func (c *WebSocketClient) WritePump() {
 	}
 }
 
+// CloseWithReason sends a close message with the given code and reason to the peer and closes the connection,
+// the reader goroutine then unregisters the client and stops the writer
+func (c *WebSocketClient) CloseWithReason(code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	if err := c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait)); err != nil {
+		log.Printf("WebSocketClient %p (%s): Error sending close message: %v", c, c.Name, err)
+	}
+	c.conn.Close()
+}
+
 // NewWebSocketClient creates a new WebSocketClient and assigns a random name
 func NewWebSocketClient(hub *WebSocketHub, conn *websocket.Conn, name string) *WebSocketClient {
 	if name == "" {